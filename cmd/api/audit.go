@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"recipe.athif.com/internal/data"
+	"recipe.athif.com/internal/validator"
+)
+
+// requestIDRX matches the request IDs accepted from clients. Anything else is replaced
+// with a generated ID, so that the audit log never holds arbitrary client input here.
+var requestIDRX = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// requestID gives every request an ID, taken from its X-Request-ID header if it has a
+// usable one, and echoes it back in the response.
+func (app *application) requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !requestIDRX.MatchString(id) {
+			b := make([]byte, 16)
+			rand.Read(b)
+			id = hex.EncodeToString(b)
+		}
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, app.contextSetRequestID(r, id))
+	})
+}
+
+// auditEntry collects what a handler knows about the resource a request acts on. The
+// states are encoded as soon as they are recorded, since handlers go on to change the
+// values they passed in.
+type auditEntry struct {
+	resourceType string
+	resourceID   int64
+	before       []byte
+	after        []byte
+}
+
+// auditBefore names the resource the request acts on and records its state before the
+// request changes it. Handlers call it once they have loaded the resource.
+func (app *application) auditBefore(r *http.Request, resourceType string, id int64, state any) {
+	app.auditState(r, resourceType, id, state, func(entry *auditEntry, js []byte) { entry.before = js })
+}
+
+// auditAfter names the resource the request acted on and records its state after a
+// successful change. Handlers which delete a resource don't call it.
+func (app *application) auditAfter(r *http.Request, resourceType string, id int64, state any) {
+	app.auditState(r, resourceType, id, state, func(entry *auditEntry, js []byte) { entry.after = js })
+}
+
+func (app *application) auditState(r *http.Request, resourceType string, id int64, state any, set func(*auditEntry, []byte)) {
+	entry := app.contextGetAudit(r)
+	if entry == nil {
+		return
+	}
+	entry.resourceType = resourceType
+	entry.resourceID = id
+
+	js, err := json.Marshal(state)
+	if err != nil {
+		app.logError(r, err)
+		return
+	}
+	set(entry, js)
+}
+
+// auditLog records every POST, PUT, PATCH and DELETE request once it has been
+// handled. The router is used to look up the route the request matched, so that
+// events for the same endpoint share a route however their IDs differ.
+//
+// The handler's response is held back until the event has been written, so a change
+// is never reported as done without a record of it: if the event can't be written the
+// client gets a 500 instead.
+func (app *application) auditLog(router *httprouter.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			router.ServeHTTP(w, r)
+			return
+		}
+
+		entry := &auditEntry{}
+		bw := newBufferedResponseWriter(w)
+		router.ServeHTTP(bw, app.contextSetAudit(r, entry))
+
+		actor := app.actor(r)
+		if actor == "" {
+			actor = "anonymous"
+		}
+		event := &data.AuditEvent{
+			Actor:        actor,
+			RequestID:    app.contextGetRequestID(r),
+			IP:           app.clientIP(r),
+			Method:       r.Method,
+			Route:        r.URL.Path,
+			Status:       bw.status,
+			ResourceType: entry.resourceType,
+			ResourceID:   entry.resourceID,
+			Changes:      []data.Change{},
+		}
+		if _, params, _ := router.Lookup(r.Method, r.URL.Path); params != nil && !dispatchedRoutes[r.Method+" "+r.URL.Path] {
+			event.Route = routePattern(r.URL.Path, params)
+		}
+		if bw.status < http.StatusBadRequest && (entry.before != nil || entry.after != nil) {
+			changes, err := data.DiffJSON(entry.before, entry.after)
+			if err != nil {
+				app.logError(r, err)
+			} else {
+				event.Changes = changes
+			}
+		}
+
+		err := app.models.Audit.Insert(event)
+		if err != nil {
+			app.serverErrorResponse(w, r, fmt.Errorf("recording audit event: %w", err))
+			return
+		}
+		bw.flush()
+	})
+}
+
+// clientIP returns the address of the client which made the request. Behind the
+// number of proxies given by the trusted-proxies setting, it is the entry the
+// outermost of them appended to X-Forwarded-For; entries to the left of it were sent
+// by the client and can't be trusted.
+func (app *application) clientIP(r *http.Request) string {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		ip = host
+	}
+	if app.config.trustedProxies == 0 {
+		return ip
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(header, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	if len(hops) < app.config.trustedProxies {
+		return ip
+	}
+	if hop := hops[len(hops)-app.config.trustedProxies]; net.ParseIP(hop) != nil {
+		return hop
+	}
+	return ip
+}
+
+// routePattern turns a request path back into the route it matched, by putting each
+// parameter's name in place of the path segment holding its value.
+func routePattern(path string, params httprouter.Params) string {
+	segments := strings.Split(path, "/")
+	for i := range segments {
+		if len(params) > 0 && segments[i] == params[0].Value {
+			segments[i] = ":" + params[0].Key
+			params = params[1:]
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// bufferedResponseWriter holds a response back, along with its status code and
+// headers, until flush is called.
+type bufferedResponseWriter struct {
+	w           http.ResponseWriter
+	header      http.Header
+	body        bytes.Buffer
+	status      int
+	wroteHeader bool
+}
+
+// newBufferedResponseWriter starts from a copy of the headers already set on w, so
+// that a response which is never flushed leaves w as it found it.
+func newBufferedResponseWriter(w http.ResponseWriter) *bufferedResponseWriter {
+	return &bufferedResponseWriter{w: w, header: w.Header().Clone(), status: http.StatusOK}
+}
+
+func (bw *bufferedResponseWriter) Header() http.Header {
+	return bw.header
+}
+
+func (bw *bufferedResponseWriter) WriteHeader(status int) {
+	if !bw.wroteHeader {
+		bw.status = status
+		bw.wroteHeader = true
+	}
+}
+
+func (bw *bufferedResponseWriter) Write(b []byte) (int, error) {
+	bw.wroteHeader = true
+	return bw.body.Write(b)
+}
+
+// flush sends the held back response to the underlying writer.
+func (bw *bufferedResponseWriter) flush() {
+	header := bw.w.Header()
+	for key := range header {
+		delete(header, key)
+	}
+	for key, values := range bw.header {
+		header[key] = values
+	}
+	bw.w.WriteHeader(bw.status)
+	bw.body.WriteTo(bw.w)
+}
+
+func (app *application) listAuditEventsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.AuditCriteria
+		data.Filters
+	}
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Actor = app.readString(qs, "actor", "")
+	input.Method = strings.ToUpper(app.readString(qs, "method", ""))
+	input.Route = app.readString(qs, "route", "")
+	input.ResourceType = app.readString(qs, "resource_type", "")
+	input.ResourceID = int64(app.readInt(qs, "resource_id", 0, v))
+	input.Since = app.readTime(qs, "since", v)
+	input.Until = app.readTime(qs, "until", v)
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "-created_at")
+	input.Filters.SortSafelist = []string{"id", "created_at", "-id", "-created_at"}
+
+	if input.Method != "" {
+		v.Check(validator.PermittedValue(input.Method, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete), "method", "must be one of POST, PUT, PATCH or DELETE")
+	}
+	v.Check(input.ResourceID >= 0, "resource_id", "must not be negative")
+	if !input.Since.IsZero() && !input.Until.IsZero() {
+		v.Check(input.Since.Before(input.Until), "until", "must be later than since")
+	}
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	events, metadata, err := app.models.Audit.GetAll(input.AuditCriteria, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"events": events, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies int
+		forwardedFor   []string
+		want           string
+	}{
+		{"no proxy", 0, []string{"203.0.113.7"}, "192.0.2.1"},
+		{"one proxy", 1, []string{"203.0.113.7"}, "203.0.113.7"},
+		{"spoofed entry", 1, []string{"198.51.100.9, 203.0.113.7"}, "203.0.113.7"},
+		{"two proxies", 2, []string{"198.51.100.9, 203.0.113.7", "10.0.0.2"}, "203.0.113.7"},
+		{"missing header", 1, nil, "192.0.2.1"},
+		{"too few entries", 2, []string{"203.0.113.7"}, "192.0.2.1"},
+		{"not an address", 1, []string{"unknown"}, "192.0.2.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &application{}
+			app.config.trustedProxies = tt.trustedProxies
+
+			r := httptest.NewRequest("POST", "/v1/recipes", nil)
+			r.RemoteAddr = "192.0.2.1:4321"
+			for _, value := range tt.forwardedFor {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if got := app.clientIP(r); got != tt.want {
+				t.Errorf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"recipe.athif.com/internal/data"
+	"recipe.athif.com/internal/validator"
+)
+
+// registerAuthorHandler registers a new author and responds with the token they
+// authenticate with. The token is only ever sent in this response.
+func (app *application) registerAuthorHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name string `json:"name"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	author := &data.Author{Name: input.Name}
+
+	v := validator.New()
+	if data.ValidateAuthor(v, author); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	token, err := app.models.Authors.Insert(author)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateAuthor):
+			v.AddError("name", "an author with this name already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	app.auditAfter(r, "author", author.ID, author)
+
+	err = app.writeJSON(w, r, http.StatusCreated, envelope{"author": author, "token": token}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// showCurrentAuthorHandler responds with the author the request's token belongs to.
+func (app *application) showCurrentAuthorHandler(w http.ResponseWriter, r *http.Request) {
+	author := app.contextGetAuthor(r)
+	if author == nil {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	err := app.writeJSON(w, r, http.StatusOK, envelope{"author": author}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
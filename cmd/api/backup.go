@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"recipe.athif.com/internal/backup"
+	"recipe.athif.com/internal/data"
+)
+
+// runBackupCommand runs the export or import subcommand and returns the process exit
+// status. The database settings come from the usual configuration layers, and -config
+// and -db-dsn may also be given on the subcommand's command line.
+func runBackupCommand(name string, args []string) int {
+	fs := flag.NewFlagSet("recipe-api "+name, flag.ContinueOnError)
+	fs.String("config", "", "Path to a YAML or TOML configuration file")
+	fs.String("db-dsn", "", "PostgreSQL DSN")
+
+	var output string
+	var opts backup.ImportOptions
+	switch name {
+	case "export":
+		fs.StringVar(&output, "o", "-", "File to write the backup to (- for standard output)")
+		fs.Usage = func() {
+			fmt.Fprintf(fs.Output(), "Usage: recipe-api export [flags]\n\nWrites every cuisine, catalog ingredient and recipe as NDJSON.\n\n")
+			fs.PrintDefaults()
+		}
+	case "import":
+		fs.IntVar(&opts.BatchSize, "batch-size", 0, "Recipes restored per transaction (0 restores everything in one transaction)")
+		fs.StringVar(&opts.ResumeAfter, "resume-after", "", "Skip recipes up to and including this uid")
+		fs.Usage = func() {
+			fmt.Fprintf(fs.Output(), "Usage: recipe-api import [flags] [file]\n\nRestores a backup written by export, from file or standard input.\n\n")
+			fs.PrintDefaults()
+		}
+	}
+
+	err := fs.Parse(args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if name == "export" && fs.NArg() > 0 || fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+
+	var cfgArgs []string
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "config" || f.Name == "db-dsn" {
+			cfgArgs = append(cfgArgs, "-"+f.Name, f.Value.String())
+		}
+	})
+	cfg, err := loadConfig(cfgArgs, os.Getenv)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	// Standard output may be carrying the backup, so progress goes to standard error.
+	logger := log.New(os.Stderr, "", log.Ldate|log.Ltime)
+
+	DB, err := openDB(cfg)
+	if err != nil {
+		logger.Print(err)
+		return 1
+	}
+	defer DB.Close()
+	models := data.NewModels(DB)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	schemaVersion, dirty, err := models.Health.SchemaVersion(ctx)
+	switch {
+	case err != nil:
+		logger.Print(err)
+		return 1
+	case dirty:
+		logger.Printf("the database schema is dirty at version %d", schemaVersion)
+		return 1
+	}
+
+	switch name {
+	case "export":
+		err = exportBackup(ctx, models.Backups, output, schemaVersion, logger)
+	case "import":
+		opts.Logf = logger.Printf
+		err = importBackup(ctx, models.Backups, fs.Arg(0), schemaVersion, opts, logger)
+	}
+	if err != nil {
+		logger.Print(err)
+		return 1
+	}
+	return 0
+}
+
+func exportBackup(ctx context.Context, m data.BackupModel, output string, schemaVersion uint, logger *log.Logger) error {
+	f := os.Stdout
+	if output != "-" {
+		var err error
+		f, err = os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+	}
+
+	trailer, err := backup.Export(ctx, m, f, schemaVersion)
+	if err != nil {
+		return err
+	}
+	// Close errors matter here, since they can mean the backup never reached the disk.
+	if f != os.Stdout {
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+
+	logger.Printf("exported %d cuisines, %d ingredients and %d recipes (%s)",
+		trailer.Cuisines, trailer.Ingredients, trailer.Recipes, trailer.Checksum)
+	return nil
+}
+
+func importBackup(ctx context.Context, m data.BackupModel, input string, schemaVersion uint, opts backup.ImportOptions, logger *log.Logger) error {
+	var r io.Reader = os.Stdin
+	if input != "" && input != "-" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	summary, err := backup.Import(ctx, m, r, schemaVersion, opts)
+	if err != nil {
+		return err
+	}
+
+	logger.Printf("restored %d cuisines and %d ingredients; %d recipes created, %d updated, %d skipped (%s)",
+		summary.Cuisines, summary.Ingredients, summary.Created, summary.Updated, summary.Skipped, summary.Checksum)
+	return nil
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+	"recipe.athif.com/internal/validator"
+)
+
+// envPrefix is prepended to the upper-cased flag name to give the environment variable
+// for a setting, so the db-max-idle-time flag can be set with RECIPE_DB_MAX_IDLE_TIME.
+const envPrefix = "RECIPE_"
+
+// legacyEnv maps settings to the unprefixed environment variables that were read before
+// configuration was layered. Heroku still sets PORT, so these must keep working.
+var legacyEnv = map[string]string{
+	"port":   "PORT",
+	"db-dsn": "DB_DSN",
+}
+
+// secretSettings holds the settings which must never be printed in clear, along with
+// the function used to redact them.
+var secretSettings = map[string]func(string) string{
+	"db-dsn":      redactDSN,
+	"admin-token": redactSecret,
+}
+
+type config struct {
+	port int
+	env  string
+	db   struct {
+		dsn          string
+		maxOpenConns int
+		maxIdleConns int
+		maxIdleTime  time.Duration
+	}
+	cors struct {
+		trustedOrigins []string
+	}
+	trash struct {
+		retention time.Duration
+	}
+	// trustedProxies is the number of reverse proxies in front of the server, whose
+	// X-Forwarded-For entries give the client's address.
+	trustedProxies int
+	adminToken     string
+	configFile     string
+	displayVersion bool
+	// settings records the effective value and source of every setting, for printing
+	// at startup.
+	settings []configSetting
+}
+
+type configSetting struct {
+	name   string
+	value  string
+	source string
+}
+
+// loadConfig builds the configuration from, in increasing order of precedence, the flag
+// defaults, the configuration file named by -config (or RECIPE_CONFIG), environment
+// variables and finally the command-line flags themselves.
+func loadConfig(args []string, getenv func(string) string) (config, error) {
+	var cfg config
+
+	fs := flag.NewFlagSet("recipe-api", flag.ContinueOnError)
+	fs.StringVar(&cfg.configFile, "config", "", "Path to a YAML or TOML configuration file")
+	fs.IntVar(&cfg.port, "port", 4000, "API server port")
+	fs.StringVar(&cfg.env, "env", "development", "Environment (development|staging|production)")
+	fs.StringVar(&cfg.db.dsn, "db-dsn", "", "PostgreSQL DSN")
+	fs.IntVar(&cfg.db.maxOpenConns, "db-max-open-conns", 25, "PostgreSQL max open connections")
+	fs.IntVar(&cfg.db.maxIdleConns, "db-max-idle-conns", 25, "PostgreSQL max idle connections")
+	fs.DurationVar(&cfg.db.maxIdleTime, "db-max-idle-time", 15*time.Minute, "PostgreSQL max connection idle time")
+	fs.Var((*fieldsValue)(&cfg.cors.trustedOrigins), "cors-trusted-origins", "Trusted CORS origins (space separated)")
+	fs.DurationVar(&cfg.trash.retention, "trash-retention", 30*24*time.Hour, "How long deleted recipes stay in the trash before they are purged (0 keeps them forever)")
+	fs.IntVar(&cfg.trustedProxies, "trusted-proxies", 0, "Number of reverse proxies in front of the server, such as 1 behind the Heroku router, whose X-Forwarded-For entries are trusted")
+	fs.StringVar(&cfg.adminToken, "admin-token", "", "Bearer token for the /v1/admin endpoints (disabled if empty)")
+	fs.BoolVar(&cfg.displayVersion, "version", false, "Display version and exit")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return cfg, err
+	}
+
+	sources := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		sources[f.Name] = "flag"
+	})
+
+	if cfg.configFile == "" {
+		cfg.configFile = getenv(envPrefix + "CONFIG")
+	}
+	fileValues := map[string]string{}
+	if cfg.configFile != "" {
+		fileValues, err = readConfigFile(cfg.configFile)
+		if err != nil {
+			return cfg, err
+		}
+		for name := range fileValues {
+			if fs.Lookup(name) == nil || name == "config" {
+				return cfg, fmt.Errorf("%s: unknown setting %q", cfg.configFile, name)
+			}
+		}
+	}
+
+	// Fill in everything that wasn't given on the command line, preferring the
+	// environment over the configuration file.
+	var setErr error
+	fs.VisitAll(func(f *flag.Flag) {
+		if setErr != nil || sources[f.Name] != "" || f.Name == "config" || f.Name == "version" {
+			return
+		}
+		source, value, ok := lookupEnv(f.Name, getenv)
+		if !ok {
+			value, ok = fileValues[f.Name]
+			source = cfg.configFile
+		}
+		if !ok {
+			return
+		}
+		if err := fs.Set(f.Name, value); err != nil {
+			setErr = fmt.Errorf("invalid value %q for %s from %s: %w", value, f.Name, source, err)
+			return
+		}
+		sources[f.Name] = source
+	})
+	if setErr != nil {
+		return cfg, setErr
+	}
+
+	fs.VisitAll(func(f *flag.Flag) {
+		if f.Name == "config" || f.Name == "version" {
+			return
+		}
+		source := sources[f.Name]
+		if source == "" {
+			source = "default"
+		}
+		value := f.Value.String()
+		if redact, ok := secretSettings[f.Name]; ok {
+			value = redact(value)
+		}
+		cfg.settings = append(cfg.settings, configSetting{name: f.Name, value: value, source: source})
+	})
+
+	if cfg.displayVersion {
+		return cfg, nil
+	}
+
+	v := validator.New()
+	if validateConfig(v, &cfg); !v.Valid() {
+		return cfg, validationError(v)
+	}
+	return cfg, nil
+}
+
+func validateConfig(v *validator.Validator, cfg *config) {
+	v.Check(validator.PermittedValue(cfg.env, "development", "staging", "production"), "env", "must be one of development, staging or production")
+	v.Check(cfg.port > 0 && cfg.port <= 65535, "port", "must be between 1 and 65535")
+	v.Check(cfg.db.dsn != "", "db-dsn", "must be provided")
+	v.Check(cfg.db.maxOpenConns > 0, "db-max-open-conns", "must be greater than zero")
+	v.Check(cfg.db.maxIdleConns >= 0, "db-max-idle-conns", "must not be negative")
+	v.Check(cfg.db.maxIdleTime > 0, "db-max-idle-time", "must be a positive duration")
+	v.Check(cfg.trash.retention >= 0, "trash-retention", "must not be negative")
+	v.Check(cfg.trustedProxies >= 0, "trusted-proxies", "must not be negative")
+	v.Check(cfg.adminToken == "" || len(cfg.adminToken) >= 32, "admin-token", "must be at least 32 characters long")
+}
+
+// validationError flattens the validator errors into a single error, sorted by key so
+// that the message is stable.
+func validationError(v *validator.Validator) error {
+	keys := make([]string, 0, len(v.Errors))
+	for key := range v.Errors {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	msgs := make([]string, len(keys))
+	for i, key := range keys {
+		msgs[i] = fmt.Sprintf("%s %s", key, v.Errors[key])
+	}
+	return fmt.Errorf("invalid configuration: %s", strings.Join(msgs, "; "))
+}
+
+// lookupEnv returns the value of the environment variable for a setting, checking the
+// prefixed name before any legacy unprefixed one.
+func lookupEnv(name string, getenv func(string) string) (string, string, bool) {
+	key := envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+	if value := getenv(key); value != "" {
+		return key, value, true
+	}
+	if key, ok := legacyEnv[name]; ok {
+		if value := getenv(key); value != "" {
+			return key, value, true
+		}
+	}
+	return "", "", false
+}
+
+// readConfigFile decodes a YAML or TOML file (chosen by extension) and flattens it into
+// flag names, so that
+//
+//	db:
+//	  max_idle_time: 5m
+//
+// sets the db-max-idle-time flag. Lists are joined with spaces.
+func readConfigFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &raw)
+	case ".toml":
+		err = toml.Unmarshal(b, &raw)
+	default:
+		return nil, fmt.Errorf("%s: unsupported configuration format (want .yaml, .yml or .toml)", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	err = flattenConfig(values, "", raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return values, nil
+}
+
+func flattenConfig(dst map[string]string, prefix string, src map[string]any) error {
+	for key, value := range src {
+		name := strings.ReplaceAll(strings.ToLower(key), "_", "-")
+		if prefix != "" {
+			name = prefix + "-" + name
+		}
+
+		switch value := value.(type) {
+		case map[string]any:
+			if err := flattenConfig(dst, name, value); err != nil {
+				return err
+			}
+		case []any:
+			items := make([]string, len(value))
+			for i, item := range value {
+				items[i] = fmt.Sprint(item)
+			}
+			dst[name] = strings.Join(items, " ")
+		case nil:
+			return fmt.Errorf("setting %q has no value", name)
+		default:
+			dst[name] = fmt.Sprint(value)
+		}
+	}
+	return nil
+}
+
+// redactDSN hides the password in a PostgreSQL connection string. Key/value DSNs which
+// can't be parsed as a URL are hidden entirely.
+func redactDSN(dsn string) string {
+	if dsn == "" {
+		return ""
+	}
+	u, err := url.Parse(dsn)
+	if err != nil || u.Scheme == "" {
+		return "REDACTED"
+	}
+	return u.Redacted()
+}
+
+// fieldsValue is a flag.Value holding a space separated list.
+type fieldsValue []string
+
+func (f *fieldsValue) String() string {
+	if f == nil {
+		return ""
+	}
+	return strings.Join(*f, " ")
+}
+
+func (f *fieldsValue) Set(val string) error {
+	*f = strings.Fields(val)
+	return nil
+}
+
+// redactSecret hides a secret entirely, while still showing whether it was set.
+func redactSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "REDACTED"
+}
+
+// printSettings logs the effective configuration, one setting per line.
+func (cfg config) printSettings(logf func(format string, v ...any)) {
+	for _, s := range cfg.settings {
+		logf("config %s=%q (%s)", s.name, s.value, s.source)
+	}
+}
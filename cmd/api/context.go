@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"recipe.athif.com/internal/data"
+)
+
+type contextKey string
+
+const (
+	requestIDContextKey = contextKey("requestID")
+	auditContextKey     = contextKey("audit")
+	authorContextKey    = contextKey("author")
+)
+
+func (app *application) contextSetRequestID(r *http.Request, id string) *http.Request {
+	ctx := context.WithValue(r.Context(), requestIDContextKey, id)
+	return r.WithContext(ctx)
+}
+
+// contextGetRequestID returns the request's ID, or an empty string for requests which
+// didn't pass through the requestID middleware.
+func (app *application) contextGetRequestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDContextKey).(string)
+	return id
+}
+
+func (app *application) contextSetAudit(r *http.Request, entry *auditEntry) *http.Request {
+	ctx := context.WithValue(r.Context(), auditContextKey, entry)
+	return r.WithContext(ctx)
+}
+
+// contextGetAudit returns the request's audit entry, or nil if the request isn't
+// being audited.
+func (app *application) contextGetAudit(r *http.Request) *auditEntry {
+	entry, _ := r.Context().Value(auditContextKey).(*auditEntry)
+	return entry
+}
+
+func (app *application) contextSetAuthor(r *http.Request, author *data.Author) *http.Request {
+	ctx := context.WithValue(r.Context(), authorContextKey, author)
+	return r.WithContext(ctx)
+}
+
+// contextGetAuthor returns the author making the request, or nil for anonymous and
+// admin requests.
+func (app *application) contextGetAuthor(r *http.Request) *data.Author {
+	author, _ := r.Context().Value(authorContextKey).(*data.Author)
+	return author
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"recipe.athif.com/internal/data"
+	"recipe.athif.com/internal/validator"
+)
+
+func (app *application) createCuisineHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name string `json:"name"`
+		Slug string `json:"slug"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	cuisine := &data.Cuisine{
+		Name: input.Name,
+		Slug: input.Slug,
+	}
+	if cuisine.Slug == "" {
+		cuisine.Slug = data.Slugify(cuisine.Name)
+	}
+
+	v := validator.New()
+	if data.ValidateCuisine(v, cuisine); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Cuisines.Insert(cuisine)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateCuisine):
+			v.AddError("name", "a cuisine with this name or slug already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	app.auditAfter(r, "cuisine", int64(cuisine.ID), cuisine)
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/cuisines/%d", cuisine.ID))
+
+	err = app.writeJSON(w, r, http.StatusCreated, envelope{"cuisine": cuisine}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showCuisineHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	cuisine, err := app.models.Cuisines.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"cuisine": cuisine}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateCuisineHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	cuisine, err := app.models.Cuisines.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	app.auditBefore(r, "cuisine", id, cuisine)
+
+	var input struct {
+		Name string `json:"name"`
+		Slug string `json:"slug"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	cuisine.Name = input.Name
+	cuisine.Slug = input.Slug
+	if cuisine.Slug == "" {
+		cuisine.Slug = data.Slugify(cuisine.Name)
+	}
+
+	v := validator.New()
+	if data.ValidateCuisine(v, cuisine); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Cuisines.Update(cuisine)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateCuisine):
+			v.AddError("name", "a cuisine with this name or slug already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	app.auditAfter(r, "cuisine", id, cuisine)
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"cuisine": cuisine}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteCuisineHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	cuisine, err := app.models.Cuisines.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	app.auditBefore(r, "cuisine", id, cuisine)
+
+	err = app.models.Cuisines.Delete(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrCuisineInUse):
+			app.conflictResponse(w, r, "the cuisine is still used by one or more recipes")
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"message": "cuisine successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listCuisinesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name string
+		data.Filters
+	}
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Name = app.readString(qs, "name", "")
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.Sort = app.readString(qs, "sort", "name")
+	input.Filters.SortSafelist = []string{"id", "name", "recipe_count", "-id", "-name", "-recipe_count"}
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	cuisines, metadata, err := app.models.Cuisines.GetAll(input.Name, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"cuisines": cuisines, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
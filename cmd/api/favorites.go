@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"recipe.athif.com/internal/data"
+	"recipe.athif.com/internal/validator"
+)
+
+// addFavoriteHandler saves the recipe named in the URL as one of the caller's
+// favorites. Saving a recipe which is already a favorite changes nothing.
+func (app *application) addFavoriteHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	if !app.recipeVisible(w, r, id) {
+		return
+	}
+
+	favorite, err := app.models.Favorites.Add(app.actor(r), id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	app.auditAfter(r, "favorite", id, favorite)
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"favorite": favorite}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) removeFavoriteHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Favorites.Remove(app.actor(r), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"message": "favorite successfully removed"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// rateRecipeHandler records the caller's rating for the recipe named in the URL,
+// replacing any earlier one.
+func (app *application) rateRecipeHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Rating *int `json:"rating"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.Rating != nil, "rating", "must be provided")
+	if input.Rating != nil {
+		data.ValidateRating(v, *input.Rating)
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if !app.recipeVisible(w, r, id) {
+		return
+	}
+
+	rating, err := app.models.Ratings.Set(app.actor(r), id, *input.Rating)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	app.auditAfter(r, "rating", id, rating)
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"rating": rating}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) removeRatingHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Ratings.Remove(app.actor(r), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"message": "rating successfully removed"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
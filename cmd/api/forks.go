@@ -0,0 +1,236 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"recipe.athif.com/internal/data"
+	"recipe.athif.com/internal/validator"
+)
+
+// forkRecipeHandler copies a recipe, with its steps, ingredients and tags, into a new
+// draft owned by the caller which records the original as its parent.
+func (app *application) forkRecipeHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	// The body is optional, and only gives the fork a title of its own.
+	var input struct {
+		Title *string `json:"title"`
+	}
+	if r.ContentLength != 0 {
+		err = app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	parent, err := app.models.Recipes.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !app.canViewRecipe(r, parent) {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	fork := &data.Recipe{
+		Title:        parent.Title,
+		Instructions: parent.Instructions,
+		Steps:        parent.Steps,
+		PrepTime:     parent.PrepTime,
+		CookTime:     parent.CookTime,
+		Difficulty:   parent.Difficulty,
+		CuisineName:  parent.CuisineName,
+		Ingredients:  parent.Ingredients,
+		Tags:         parent.Tags,
+		ImageLink:    parent.ImageLink,
+		Status:       data.StatusDraft,
+		ParentID:     parent.ID,
+	}
+	if input.Title != nil {
+		fork.Title = *input.Title
+	}
+
+	v := validator.New()
+	durationFormat := app.readDurationFormat(r.URL.Query(), v)
+	if data.ValidateRecipe(v, fork); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Recipes.Insert(fork, app.actor(r))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	app.auditAfter(r, "recipe", int64(fork.ID), fork)
+
+	fork.SetDurationFormat(durationFormat)
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/recipes/%d", fork.ID))
+
+	err = app.writeJSON(w, r, http.StatusCreated, envelope{"recipe": fork}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listVariationsHandler lists the direct forks of a recipe.
+func (app *application) listVariationsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	if !app.recipeVisible(w, r, id) {
+		return
+	}
+
+	var input struct {
+		data.RecipeCriteria
+		data.Filters
+	}
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.ParentID = int(id)
+	app.readStatusCriteria(r, qs, &input.RecipeCriteria, v)
+	durationFormat := app.readDurationFormat(qs, v)
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "id")
+	input.Filters.SortSafelist = []string{"id", "title", "-id", "-title"}
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	recipes, metadata, err := app.models.Recipes.GetAll(input.RecipeCriteria, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	for _, recipe := range recipes {
+		recipe.SetDurationFormat(durationFormat)
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"recipes": recipes, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// showLineageHandler shows the line of forks leading to a recipe, from the original
+// down to the recipe itself. Ancestors the caller can't see, or which are in the
+// trash, are listed by ID only.
+func (app *application) showLineageHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	lineage, err := app.models.Recipes.GetLineage(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	for i, entry := range lineage {
+		visible := !entry.Deleted && (entry.Status == data.StatusPublished || app.isAuthor(r, entry.Author))
+		if visible {
+			continue
+		}
+		if i == len(lineage)-1 {
+			app.notFoundResponse(w, r)
+			return
+		}
+		entry.Title = ""
+		entry.Status = ""
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"lineage": lineage}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// diffRecipeHandler lists the changes from another recipe, by default the recipe's
+// parent, to the recipe in the URL. Ingredients are compared by name, so a fork which
+// only cuts the sugar shows up as a change to ingredients[sugar].
+func (app *application) diffRecipeHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var recipes [2]*data.Recipe
+	recipes[1], err = app.models.Recipes.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !app.canViewRecipe(r, recipes[1]) {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+	against := app.readInt(r.URL.Query(), "against", recipes[1].ParentID, v)
+	v.Check(against > 0, "against", "must be given for a recipe which isn't a fork")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	recipes[0], err = app.models.Recipes.Get(int64(against))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !app.canViewRecipe(r, recipes[0]) {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	diff := envelope{
+		"from":    against,
+		"to":      id,
+		"changes": data.DiffRecipes(recipes[0], recipes[1]),
+	}
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"diff": diff}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -1,21 +1,133 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"time"
+
+	"recipe.athif.com/migrations"
 )
 
-func (app *application) healthcheckHandler(w http.ResponseWriter, r *http.Request) {
+// readinessTimeout bounds how long the readiness probe waits on each dependency.
+const readinessTimeout = 2 * time.Second
+
+var (
+	errDirtyMigration    = errors.New("the last migration failed and must be fixed by hand")
+	errPendingMigrations = errors.New("database schema is behind the application")
+)
+
+// dependencyCheck reports the health of one backing service. It returns details to
+// include in the response, and an error if the service is unusable.
+type dependencyCheck func(ctx context.Context) (map[string]any, error)
+
+// healthzHandler is the liveness probe: it only reports that the process is up and able
+// to serve requests, without touching any dependency.
+func (app *application) healthzHandler(w http.ResponseWriter, r *http.Request) {
 	env := envelope{
-		"status": "available",
-		"system_info": map[string]string{
-			"environment": app.config.env,
-			"version":     version,
-		},
+		"status":      "available",
+		"system_info": app.systemInfo(),
 	}
-	err := app.writeJSON(w, http.StatusOK, env, nil)
+	err := app.writeJSON(w, r, http.StatusOK, env, nil)
 
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 }
+
+// readyzHandler is the readiness probe. It checks every dependency and responds with
+// 503 Service Unavailable if any of them is unhealthy.
+func (app *application) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]dependencyCheck{
+		"database":   app.checkDatabase,
+		"migrations": app.checkMigrations,
+	}
+
+	status := http.StatusOK
+	results := make(map[string]any, len(checks))
+	for name, check := range checks {
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		detail, err := check(ctx)
+		cancel()
+
+		if detail == nil {
+			detail = map[string]any{}
+		}
+		if err != nil {
+			status = http.StatusServiceUnavailable
+			detail["status"] = "down"
+			detail["error"] = err.Error()
+		} else {
+			detail["status"] = "up"
+		}
+		results[name] = detail
+	}
+
+	env := envelope{
+		"status":       "available",
+		"dependencies": results,
+		"system_info":  app.systemInfo(),
+	}
+	if status != http.StatusOK {
+		env["status"] = "unavailable"
+	}
+
+	err := app.writeJSON(w, r, status, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) checkDatabase(ctx context.Context) (map[string]any, error) {
+	start := time.Now()
+	err := app.models.Health.Ping(ctx)
+	stats := app.models.Health.Stats()
+
+	detail := map[string]any{
+		"latency_ms": time.Since(start).Milliseconds(),
+		"pool": map[string]any{
+			"max_open_connections": stats.MaxOpenConnections,
+			"open_connections":     stats.OpenConnections,
+			"in_use":               stats.InUse,
+			"idle":                 stats.Idle,
+			"wait_count":           stats.WaitCount,
+			"wait_duration_ms":     stats.WaitDuration.Milliseconds(),
+		},
+	}
+	return detail, err
+}
+
+func (app *application) checkMigrations(ctx context.Context) (map[string]any, error) {
+	latest, err := migrations.Latest()
+	if err != nil {
+		return nil, err
+	}
+
+	current, dirty, err := app.models.Health.SchemaVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	detail := map[string]any{
+		"current": current,
+		"latest":  latest,
+		"dirty":   dirty,
+	}
+	switch {
+	case dirty:
+		return detail, errDirtyMigration
+	case current < latest:
+		return detail, errPendingMigrations
+	}
+	return detail, nil
+}
+
+func (app *application) systemInfo() map[string]string {
+	return map[string]string{
+		"environment": app.config.env,
+		"version":     version,
+		"git_commit":  gitCommit,
+		"build_time":  buildTime,
+	}
+}
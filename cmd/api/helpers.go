@@ -1,6 +1,8 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,8 +11,11 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
+	"recipe.athif.com/internal/data"
+	"recipe.athif.com/internal/ingredientparse"
 	"recipe.athif.com/internal/validator"
 )
 
@@ -25,16 +30,41 @@ func (app *application) readIDParam(r *http.Request) (int64, error) {
 	return id, nil
 }
 
+// readRevisionParam reads the "rev" URL parameter in the same way.
+func (app *application) readRevisionParam(r *http.Request) (int, error) {
+	params := httprouter.ParamsFromContext(r.Context())
+	rev, err := strconv.Atoi(params.ByName("rev"))
+	if err != nil || rev < 1 {
+		return 0, errors.New("invalid rev parameter")
+	}
+	return rev, nil
+}
+
 type envelope map[string]any
 
-func (app *application) writeJSON(w http.ResponseWriter, status int, data envelope, headers http.Header) error {
+// encodeJSON encodes the data compactly, or indented with tabs if the client asked for
+// ?pretty=true.
+func (app *application) encodeJSON(r *http.Request, data envelope) ([]byte, error) {
+	var js []byte
+	var err error
+	if r.URL.Query().Get("pretty") == "true" {
+		js, err = json.MarshalIndent(data, "", "\t")
+	} else {
+		js, err = json.Marshal(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Append a newline to make it easier to view in terminal applications.
+	return append(js, '\n'), nil
+}
+
+func (app *application) writeJSON(w http.ResponseWriter, r *http.Request, status int, data envelope, headers http.Header) error {
 	// Encode the data to JSON, returning the error if there was one.
-	js, err := json.MarshalIndent(data, "", "\t")
+	js, err := app.encodeJSON(r, data)
 	if err != nil {
 		return err
 	}
-	// Append a newline to make it easier to view in terminal applications.
-	js = append(js, '\n')
 
 	for key, value := range headers {
 		w.Header()[key] = value
@@ -47,6 +77,72 @@ func (app *application) writeJSON(w http.ResponseWriter, status int, data envelo
 	return nil
 }
 
+// writeCachedJSON writes a 200 OK JSON response carrying a strong ETag computed from the
+// encoded body and, if lastModified is non-zero, a Last-Modified header. If the request
+// preconditions show that the client already has this representation, a 304 Not
+// Modified is sent instead.
+func (app *application) writeCachedJSON(w http.ResponseWriter, r *http.Request, data envelope, lastModified time.Time) error {
+	js, err := app.encodeJSON(r, data)
+	if err != nil {
+		return err
+	}
+	app.writeCached(w, r, "application/json", js, lastModified)
+	return nil
+}
+
+// writeCached is writeCachedJSON for a body which has already been rendered.
+func (app *application) writeCached(w http.ResponseWriter, r *http.Request, contentType string, body []byte, lastModified time.Time) {
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if !lastModified.IsZero() {
+		lastModified = lastModified.UTC().Truncate(time.Second)
+		w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+	}
+
+	if notModified(r, etag, lastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// notModified evaluates If-None-Match and, only when that is absent, If-Modified-Since,
+// as described in RFC 9110 section 13.2.2.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagMatches(inm, etag)
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !lastModified.IsZero() {
+		t, err := http.ParseTime(ims)
+		return err == nil && !lastModified.After(t)
+	}
+	return false
+}
+
+// etagMatches reports whether any entity tag in an If-None-Match header matches etag.
+// The comparison is weak, and ignores the content-coding suffix which compressResponse
+// adds to the ETag of compressed responses.
+func etagMatches(header, etag string) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		for _, encoding := range supportedEncodings {
+			candidate = strings.Replace(candidate, "-"+encoding+`"`, `"`, 1)
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
 func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any) error {
 	maxBytes := 1_048_576
 	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes))
@@ -108,7 +204,10 @@ func (app *application) readString(qs url.Values, key string, defaultValue strin
 	return s
 }
 
-
+// The readInt() helper reads a string value from the query string and converts it to an
+// integer before returning. If no matching key could be found it returns the provided
+// default value. If the value couldn't be converted to an integer, then we record an
+// error message in the provided Validator instance.
 func (app *application) readInt(qs url.Values, key string, defaultValue int, v *validator.Validator) int {
 	// Extract the value from the query string.
 	s := qs.Get(key)
@@ -140,7 +239,213 @@ func (app *application) readCSV(qs url.Values, key string, defaultValue []string
 	return strings.Split(csv, ",")
 }
 
-// The readInt() helper reads a string value from the query string and converts it to an
-// integer before returning. If no matching key could be found it returns the provided
-// default value. If the value couldn't be converted to an integer, then we record an
-// error message in the provided Validator instance.
\ No newline at end of file
+// The readBool() helper reads a boolean value from the query string, accepting the
+// forms understood by strconv.ParseBool. If no matching key could be found it returns
+// the provided default value, and an unparseable value is recorded in the Validator.
+func (app *application) readBool(qs url.Values, key string, defaultValue bool, v *validator.Validator) bool {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		v.AddError(key, "must be a boolean value")
+		return defaultValue
+	}
+	return b
+}
+
+// The readTime() helper reads an RFC 3339 timestamp from the query string. If no
+// matching key could be found it returns the zero time, and a malformed timestamp is
+// recorded in the provided Validator.
+func (app *application) readTime(qs url.Values, key string, v *validator.Validator) time.Time {
+	s := qs.Get(key)
+	if s == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		v.AddError(key, "must be an RFC 3339 timestamp")
+		return time.Time{}
+	}
+	return t
+}
+
+// The readStatusCriteria() helper reads the status parameter, which defaults to
+// published, into the criteria. Unpublished recipes are only listed to their author,
+// so for any other status the listing is narrowed to the caller's own recipes unless
+// the caller is an admin, and anonymous callers may only list published recipes. An
+// unknown status is recorded in the provided Validator.
+func (app *application) readStatusCriteria(r *http.Request, qs url.Values, criteria *data.RecipeCriteria, v *validator.Validator) {
+	criteria.Status = data.Status(app.readString(qs, "status", string(data.StatusPublished)))
+	v.Check(validator.PermittedValue(criteria.Status, data.Statuses...), "status", "must be one of draft, pending_review, published or rejected")
+	if criteria.Status != data.StatusPublished && !app.isAdmin(r) {
+		criteria.Author = app.actor(r)
+		v.Check(criteria.Author != "", "status", "must be published unless an author token is given")
+	}
+}
+
+// The background() helper runs fn in a new goroutine, recovering and logging any panic
+// so that it can't bring the server down.
+func (app *application) background(fn func()) {
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				app.logger.Printf("background task panicked: %v", err)
+			}
+		}()
+		fn()
+	}()
+}
+
+// The readDurationFormat() helper reads the duration_format parameter, which chooses
+// how recipe times are written in the response. An unknown format is recorded in the
+// provided Validator.
+func (app *application) readDurationFormat(qs url.Values, v *validator.Validator) data.DurationFormat {
+	format := data.DurationFormat(app.readString(qs, "duration_format", string(data.DurationFormats[0])))
+	v.Check(validator.PermittedValue(format, data.DurationFormats...), "duration_format", "must be one of mins, human, iso8601 or minutes")
+	return format
+}
+
+// The readRecipeLimits() helper reads the difficulty parameters along with the
+// max_total_time, max_prep_time and max_cook_time parameters, which take any duration
+// accepted by data.Mins. Any problems are recorded in the provided Validator.
+func (app *application) readRecipeLimits(qs url.Values, v *validator.Validator) data.RecipeLimits {
+	limits := data.RecipeLimits{Difficulty: app.readDifficultyRange(qs, v)}
+	for key, dest := range map[string]*data.Mins{
+		"max_total_time": &limits.MaxTotalTime,
+		"max_prep_time":  &limits.MaxPrepTime,
+		"max_cook_time":  &limits.MaxCookTime,
+	} {
+		if s := qs.Get(key); s != "" {
+			m, err := data.ParseMins(s)
+			if err != nil {
+				v.AddError(key, "must be a duration such as 30 or 1h30m")
+			}
+			*dest = m
+		}
+	}
+	return limits
+}
+
+// The readDifficultyRange() helper reads the difficulty, min_difficulty and
+// max_difficulty parameters. A single difficulty selects just that level and takes
+// precedence over the range. Unknown levels are recorded in the provided Validator.
+func (app *application) readDifficultyRange(qs url.Values, v *validator.Validator) data.DifficultyRange {
+	read := func(key string) data.Difficulty {
+		s := qs.Get(key)
+		if s == "" {
+			return ""
+		}
+		d, err := data.ParseDifficulty(s)
+		if err != nil {
+			v.AddError(key, "must be one of easy, medium or advanced")
+		}
+		return d
+	}
+
+	if d := read("difficulty"); d != "" {
+		return data.DifficultyRange{Min: d, Max: d}
+	}
+	dr := data.DifficultyRange{Min: read("min_difficulty"), Max: read("max_difficulty")}
+	v.Check(dr.Min == "" || dr.Max == "" || dr.Min.Rank() <= dr.Max.Rank(), "min_difficulty", "must not be harder than max_difficulty")
+	return dr
+}
+
+// recipeFormats maps each format a single recipe can be rendered in to its media type.
+var recipeFormats = map[string]string{
+	"json":     "application/json",
+	"jsonld":   "application/ld+json",
+	"markdown": "text/markdown",
+	"html":     "text/html",
+	"pdf":      "application/pdf",
+}
+
+// recipeFormatOrder lists the formats in order of preference, for choosing between
+// formats the Accept header rates equally.
+var recipeFormatOrder = []string{"json", "jsonld", "html", "markdown", "pdf"}
+
+// readRecipeFormat picks the format to render a recipe in. The format query string
+// parameter wins; otherwise the format the Accept header gives the highest q-value is
+// used, with JSON preferred on a tie (so */* means JSON) and as the default. Each
+// format takes the q-value of the most specific media range matching it, so
+// "text/html;q=0" rules out HTML even alongside */*. The returned bool is false when
+// the client asked for something we can't produce.
+func (app *application) readRecipeFormat(r *http.Request, v *validator.Validator) (string, bool) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		_, ok := recipeFormats[format]
+		v.Check(ok, "format", "must be one of json, jsonld, markdown, html or pdf")
+		return format, true
+	}
+
+	accept := r.Header.Get("Accept")
+	if strings.TrimSpace(accept) == "" {
+		return "json", true
+	}
+
+	best, bestQ := "", 0.0
+	for _, format := range recipeFormatOrder {
+		if q := acceptQuality(accept, recipeFormats[format]); q > bestQ {
+			best, bestQ = format, q
+		}
+	}
+	return best, best != ""
+}
+
+// acceptQuality returns the q-value an Accept header gives a media type, taken from the
+// most specific media range which matches it, or zero if none does. Media ranges with
+// an unreadable q-value are ignored.
+func acceptQuality(accept, mediaType string) float64 {
+	typ, _, _ := strings.Cut(mediaType, "/")
+
+	q, specificity := 0.0, 0
+	for _, mediaRange := range strings.Split(accept, ",") {
+		name, params, _ := strings.Cut(mediaRange, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		var s int
+		switch name {
+		case mediaType:
+			s = 3
+		case typ + "/*":
+			s = 2
+		case "*/*":
+			s = 1
+		default:
+			continue
+		}
+		if s <= specificity {
+			continue
+		}
+
+		rangeQ, ok := 1.0, true
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(param, "=")
+			if strings.ToLower(strings.TrimSpace(key)) != "q" {
+				continue
+			}
+			f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			rangeQ, ok = f, err == nil && f >= 0 && f <= 1
+		}
+		if ok {
+			q, specificity = rangeQ, s
+		}
+	}
+	return q
+}
+
+// parseIngredientLines parses free-text ingredient lines and appends them to
+// ingredients, adding a validation error for any line without an ingredient name. The
+// result is nil only when both are nil, so that omitting both still means "unchanged"
+// on update.
+func (app *application) parseIngredientLines(ingredients []data.Ingredient, lines []string, v *validator.Validator) []data.Ingredient {
+	for i, raw := range lines {
+		line := ingredientparse.Parse(raw)
+		v.Check(line.Name != "", "ingredient_lines", fmt.Sprintf("line %d has no ingredient name", i+1))
+		ingredients = append(ingredients, line.Ingredient())
+	}
+	if ingredients == nil && lines != nil {
+		ingredients = []data.Ingredient{}
+	}
+	return ingredients
+}
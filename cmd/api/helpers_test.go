@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"recipe.athif.com/internal/validator"
+)
+
+func TestReadRecipeFormat(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+		ok     bool
+	}{
+		{"", "json", true},
+		{"*/*", "json", true},
+		{"application/json", "json", true},
+		{"text/html", "html", true},
+		{"text/*", "html", true},
+		{"text/markdown", "markdown", true},
+		{"application/json, text/html", "json", true},
+		{"text/html, application/json", "json", true},
+		{"text/markdown, text/html", "html", true},
+		{"text/html;q=0.5, application/pdf", "pdf", true},
+		{"text/html;q=0, */*", "json", true},
+		{"text/html;q=0", "", false},
+		{"application/json;q=0.1, text/markdown;q=0.9", "markdown", true},
+		{"text/*;q=0.2, text/markdown;q=0.8, */*;q=0.1", "markdown", true},
+		{"text/html;q=oops, application/ld+json", "jsonld", true},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", "html", true},
+		{"image/png", "", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/v1/recipes/1", nil)
+		if tt.accept != "" {
+			r.Header.Set("Accept", tt.accept)
+		}
+		app := &application{}
+		got, ok := app.readRecipeFormat(r, validator.New())
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("readRecipeFormat(Accept: %q) = %q, %v, want %q, %v", tt.accept, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,570 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"recipe.athif.com/internal/data"
+	"recipe.athif.com/internal/schemaorg"
+	"recipe.athif.com/internal/validator"
+)
+
+const (
+	// maxImportBytes caps the size of an uploaded import file.
+	maxImportBytes = 10 << 20
+	// syncImportLimit is the largest import which is processed while the client waits;
+	// anything bigger runs as a background job.
+	syncImportLimit = 100
+	// importBatchSize is the number of recipes inserted per transaction.
+	importBatchSize = 100
+)
+
+// importRecord is one recipe read from an import file, along with any problems found
+// while reading or validating it.
+type importRecord struct {
+	key    string
+	recipe *data.Recipe
+	errors map[string]string
+}
+
+type importResult struct {
+	Index  int               `json:"index"`
+	Key    string            `json:"key,omitempty"`
+	Status string            `json:"status"`
+	ID     int               `json:"id,omitempty"`
+	Title  string            `json:"title,omitempty"`
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+type importReport struct {
+	DryRun  bool           `json:"dry_run"`
+	Total   int            `json:"total"`
+	Created int            `json:"created"`
+	Valid   int            `json:"valid"`
+	Failed  int            `json:"failed"`
+	Results []importResult `json:"results"`
+}
+
+// recipePostHandler serves POST /v1/recipes/import, which is routed as
+// POST /v1/recipes/:id. No recipe accepts a POST of its own, so any other ID is
+// answered as the router would have answered it before.
+func (app *application) recipePostHandler(w http.ResponseWriter, r *http.Request) {
+	if httprouter.ParamsFromContext(r.Context()).ByName("id") != "import" {
+		w.Header().Set("Allow", "GET, PUT, DELETE, OPTIONS")
+		app.methodNotAllowedResponse(w, r)
+		return
+	}
+	app.requireAdmin(app.createImportHandler)(w, r)
+}
+
+func (app *application) createImportHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	dryRun := app.readBool(qs, "dry_run", false, v)
+	async := app.readBool(qs, "async", false, v)
+	format := app.readString(qs, "format", "")
+	v.Check(format == "" || validator.PermittedValue(format, "ndjson", "csv"), "format", "must be ndjson or csv")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	body, filename, err := app.readImportBody(w, r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if format == "" {
+		format = importFormat(r.Header.Get("Content-Type"), filename)
+	}
+
+	var records []importRecord
+	switch format {
+	case "csv":
+		records, err = readImportCSV(body)
+	case "ndjson":
+		records, err = readImportNDJSON(body)
+	default:
+		err = errors.New("unknown import format; set format=ndjson or format=csv")
+	}
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if len(records) == 0 {
+		app.badRequestResponse(w, r, errors.New("import file contains no recipes"))
+		return
+	}
+	author := app.actor(r)
+
+	if !async && len(records) <= syncImportLimit {
+		report, err := app.runImport(records, dryRun, author, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		err = app.writeJSON(w, r, http.StatusOK, envelope{"import": report}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	job := &importJob{Status: jobQueued, DryRun: dryRun, Total: len(records), CreatedAt: time.Now()}
+	app.jobs.add(job)
+	id := job.ID
+
+	app.background(func() {
+		app.jobs.update(id, func(job *importJob) { job.Status = jobRunning })
+
+		report, err := app.runImport(records, dryRun, author, func(processed int) {
+			app.jobs.update(id, func(job *importJob) { job.Processed = processed })
+		})
+
+		app.jobs.update(id, func(job *importJob) {
+			finished := time.Now()
+			job.FinishedAt = &finished
+			if err != nil {
+				app.logger.Printf("import job %d: %v", id, err)
+				job.Status = jobFailed
+				job.Error = "the import could not be completed"
+				return
+			}
+			job.Status = jobCompleted
+			job.Report = report
+		})
+	})
+
+	snapshot, _ := app.jobs.get(id)
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/imports/%d", id))
+
+	err = app.writeJSON(w, r, http.StatusAccepted, envelope{"job": snapshot}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showImportHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	job, ok := app.jobs.get(id)
+	if !ok {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"job": job}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// runImport inserts the valid records in batches, attributed to author, and reports on
+// every record. The progress function, if given, is called with the number of records
+// processed so far.
+func (app *application) runImport(records []importRecord, dryRun bool, author string, progress func(int)) (*importReport, error) {
+	report := &importReport{DryRun: dryRun, Total: len(records), Results: make([]importResult, len(records))}
+
+	var pending []int
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		// Only admins can import, so imported recipes skip review and are published.
+		recipes := make([]*data.Recipe, len(pending))
+		for i, index := range pending {
+			recipes[i] = records[index].recipe
+			recipes[i].Status = data.StatusPublished
+		}
+
+		errs, err := app.models.Recipes.InsertBatch(recipes, dryRun, author)
+		if err != nil {
+			return err
+		}
+		for i, index := range pending {
+			result := &report.Results[index]
+			switch {
+			case errs[i] == nil && dryRun:
+				result.Status = "valid"
+			case errs[i] == nil:
+				result.Status = "created"
+				result.ID = recipes[i].ID
+			default:
+				result.Status = "failed"
+				result.Errors = app.importErrors(errs[i])
+			}
+		}
+
+		pending = pending[:0]
+		return nil
+	}
+
+	for i, record := range records {
+		result := &report.Results[i]
+		result.Index = i + 1
+		result.Key = record.key
+
+		if record.recipe != nil {
+			result.Title = record.recipe.Title
+		}
+		if record.errors == nil {
+			record.recipe.Tags = data.NormalizeTags(record.recipe.Tags)
+			record.recipe.SyncInstructions()
+			v := validator.New()
+			if data.ValidateRecipe(v, record.recipe); !v.Valid() {
+				record.errors = v.Errors
+			}
+		}
+		if record.errors != nil {
+			result.Status = "failed"
+			result.Errors = record.errors
+			continue
+		}
+
+		pending = append(pending, i)
+		if len(pending) == importBatchSize {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			if progress != nil {
+				progress(i + 1)
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	if progress != nil {
+		progress(len(records))
+	}
+
+	for _, result := range report.Results {
+		switch result.Status {
+		case "created":
+			report.Created++
+		case "valid":
+			report.Valid++
+		default:
+			report.Failed++
+		}
+	}
+	return report, nil
+}
+
+// importErrors turns an error from inserting a recipe into validation-style messages.
+func (app *application) importErrors(err error) map[string]string {
+	switch {
+	case errors.Is(err, data.ErrUnknownCuisine):
+		return map[string]string{"cuisine_name": "unknown cuisine"}
+	default:
+		app.logger.Printf("import: %v", err)
+		return map[string]string{"recipe": "could not be saved"}
+	}
+}
+
+// readImportBody returns the uploaded file, which is either the request body itself or
+// the "file" part of a multipart form, along with its file name if it has one.
+func (app *application) readImportBody(w http.ResponseWriter, r *http.Request) (io.Reader, string, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var body io.Reader = r.Body
+	filename := ""
+	if mediaType == "multipart/form-data" {
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			return nil, "", fmt.Errorf("multipart upload must include a file field: %w", err)
+		}
+		body = file
+		filename = header.Filename
+	}
+
+	b, err := io.ReadAll(body)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			return nil, "", fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit)
+		}
+		return nil, "", err
+	}
+	return bytes.NewReader(b), filename, nil
+}
+
+// importFormat guesses the format of an upload from its content type or file name.
+func importFormat(contentType, filename string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "text/csv" || strings.EqualFold(filepath.Ext(filename), ".csv"):
+		return "csv"
+	case mediaType == "application/x-ndjson" || mediaType == "application/jsonl" || mediaType == "application/json":
+		return "ndjson"
+	case strings.EqualFold(filepath.Ext(filename), ".ndjson") || strings.EqualFold(filepath.Ext(filename), ".jsonl"):
+		return "ndjson"
+	}
+	return ""
+}
+
+// readImportNDJSON reads one recipe per line, each in the same form as the body of
+// POST /v1/recipes with an optional "key" to identify it in the report. Blank lines are
+// skipped.
+func readImportNDJSON(r io.Reader) ([]importRecord, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1_048_576)
+
+	records := []importRecord{}
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		var input struct {
+			Key          string            `json:"key"`
+			Title        string            `json:"title"`
+			Instructions string            `json:"instructions"`
+			Steps        []data.Step       `json:"steps"`
+			Tags         []string          `json:"tags"`
+			PrepTime     data.Mins         `json:"prep_time"`
+			CookTime     data.Mins         `json:"cook_time"`
+			CuisineName  string            `json:"cuisine_name"`
+			Difficulty   data.Difficulty   `json:"difficulty"`
+			Ingredients  []data.Ingredient `json:"ingredients"`
+		}
+
+		record := importRecord{key: fmt.Sprintf("line %d", line)}
+		dec := json.NewDecoder(bytes.NewReader(text))
+		dec.DisallowUnknownFields()
+		err := dec.Decode(&input)
+		if err != nil {
+			record.errors = map[string]string{"record": fmt.Sprintf("invalid JSON: %v", err)}
+			records = append(records, record)
+			continue
+		}
+
+		if input.Key != "" {
+			record.key = input.Key
+		}
+		record.recipe = &data.Recipe{
+			Title:        input.Title,
+			Instructions: input.Instructions,
+			Steps:        input.Steps,
+			Tags:         input.Tags,
+			PrepTime:     input.PrepTime,
+			CookTime:     input.CookTime,
+			CuisineName:  input.CuisineName,
+			Difficulty:   input.Difficulty,
+			Ingredients:  input.Ingredients,
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("line %d: %w", line+1, err)
+	}
+	return records, nil
+}
+
+// csvImportColumns are the columns an import CSV may have. The file has one row per
+// ingredient line, and rows with the same key belong to the same recipe; the recipe
+// columns are taken from the first row of each recipe which fills them in. Tags are
+// separated by semicolons.
+var csvImportColumns = []string{
+	"key", "title", "instructions", "prep_time", "cook_time", "difficulty", "cuisine_name", "tags",
+	"ingredient_name", "quantity", "unit",
+}
+
+func readImportCSV(r io.Reader) ([]importRecord, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return []importRecord{}, nil
+		}
+		return nil, err
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !validator.PermittedValue(name, csvImportColumns...) {
+			return nil, fmt.Errorf("CSV header has unknown column %q", name)
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["key"]; !ok {
+		return nil, errors.New(`CSV header must include a "key" column`)
+	}
+
+	records := []importRecord{}
+	byKey := make(map[string]int)
+	row := 1
+	for {
+		fields, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		row++
+		if err != nil {
+			return nil, err
+		}
+
+		get := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(fields) {
+				return ""
+			}
+			return strings.TrimSpace(fields[i])
+		}
+
+		key := get("key")
+		if key == "" {
+			return nil, fmt.Errorf("row %d: key must be provided", row)
+		}
+		index, ok := byKey[key]
+		if !ok {
+			index = len(records)
+			byKey[key] = index
+			records = append(records, importRecord{key: key, recipe: &data.Recipe{Ingredients: []data.Ingredient{}}})
+		}
+		record := &records[index]
+		recipe := record.recipe
+
+		addError := func(field, message string) {
+			if record.errors == nil {
+				record.errors = make(map[string]string)
+			}
+			if _, exists := record.errors[field]; !exists {
+				record.errors[field] = fmt.Sprintf("row %d: %s", row, message)
+			}
+		}
+
+		setString := func(dst *string, name string) {
+			if *dst == "" {
+				*dst = get(name)
+			}
+		}
+		setString(&recipe.Title, "title")
+		setString(&recipe.Instructions, "instructions")
+		setString(&recipe.CuisineName, "cuisine_name")
+
+		for name, dst := range map[string]*data.Mins{"prep_time": &recipe.PrepTime, "cook_time": &recipe.CookTime} {
+			if s := get(name); s != "" && *dst == 0 {
+				m, err := data.ParseMins(s)
+				if err != nil {
+					addError(name, "must be a duration such as 30 or 1h30m")
+					continue
+				}
+				*dst = m
+			}
+		}
+		if s := get("difficulty"); s != "" && recipe.Difficulty == "" {
+			d, err := data.ParseDifficulty(s)
+			if err != nil {
+				addError("difficulty", "must be one of Easy, Medium or Advanced")
+			} else {
+				recipe.Difficulty = d
+			}
+		}
+		if s := get("tags"); s != "" && recipe.Tags == nil {
+			recipe.Tags = strings.Split(s, ";")
+		}
+
+		if name := get("ingredient_name"); name != "" {
+			ingredient := data.Ingredient{IngredientName: name, Unit: get("unit")}
+			if s := get("quantity"); s != "" {
+				q, err := strconv.ParseFloat(s, 32)
+				if err != nil {
+					addError("ingredients", "quantity must be a number")
+				}
+				ingredient.Quantity = float32(q)
+			}
+			recipe.Ingredients = append(recipe.Ingredients, ingredient)
+		}
+	}
+	return records, nil
+}
+
+// importSchemaOrgHandler extracts a schema.org Recipe from an uploaded HTML page or
+// JSON-LD document and returns it as a draft for review. Nothing is saved; the draft
+// can be corrected and then sent to POST /v1/recipes.
+func (app *application) importSchemaOrgHandler(w http.ResponseWriter, r *http.Request) {
+	body, filename, err := app.readImportBody(w, r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	doc, err := io.ReadAll(body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var draft *schemaorg.Draft
+	if isJSONLD(r.Header.Get("Content-Type"), filename, doc) {
+		draft, err = schemaorg.ExtractJSONLD(doc)
+	} else {
+		draft, err = schemaorg.ExtractHTML(doc)
+	}
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if draft.Recipe.CuisineName != "" {
+		cuisine, err := app.models.Cuisines.Lookup(draft.Recipe.CuisineName)
+		switch {
+		case err == nil:
+			draft.Recipe.CuisineName = cuisine.Name
+		case errors.Is(err, data.ErrUnknownCuisine):
+			draft.Warnings = append(draft.Warnings, fmt.Sprintf("cuisine %q doesn't exist yet", draft.Recipe.CuisineName))
+		default:
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"recipe": draft.Recipe, "warnings": draft.Warnings}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// isJSONLD decides whether an upload is JSON-LD rather than HTML, from its content
+// type, its file name or failing those whether it starts like JSON.
+func isJSONLD(contentType, filename string, doc []byte) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/ld+json" || mediaType == "application/json":
+		return true
+	case mediaType == "text/html":
+		return false
+	case strings.EqualFold(filepath.Ext(filename), ".json") || strings.EqualFold(filepath.Ext(filename), ".jsonld"):
+		return true
+	}
+	doc = bytes.TrimSpace(doc)
+	return len(doc) > 0 && (doc[0] == '{' || doc[0] == '[')
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"recipe.athif.com/internal/data"
+)
+
+// TestReadImportCSVBadValues checks that values which don't parse are reported and
+// leave the recipe's field unset, rather than being stored as zero or as given.
+func TestReadImportCSVBadValues(t *testing.T) {
+	csv := "key,title,prep_time,cook_time,difficulty,ingredient_name\n" +
+		"a,Soup,soon,20,Tricky,leek\n" +
+		"a,,15,,Easy,stock\n"
+
+	records, err := readImportCSV(strings.NewReader(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("read %d records, want 1", len(records))
+	}
+	record := records[0]
+	for _, field := range []string{"prep_time", "difficulty"} {
+		if !strings.HasPrefix(record.errors[field], "row 2:") {
+			t.Errorf("errors[%q] = %q, want an error for row 2", field, record.errors[field])
+		}
+	}
+	// The later row fills in what the first couldn't.
+	recipe := record.recipe
+	if recipe.PrepTime != 15 || recipe.CookTime != 20 || recipe.Difficulty != data.DifficultyEasy {
+		t.Errorf("recipe has prep_time %d, cook_time %d and difficulty %q, want 15, 20 and Easy",
+			recipe.PrepTime, recipe.CookTime, recipe.Difficulty)
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"recipe.athif.com/internal/data"
+	"recipe.athif.com/internal/ingredientparse"
+	"recipe.athif.com/internal/validator"
+)
+
+func (app *application) listIngredientsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Prefix   string
+		Category string
+		data.Filters
+	}
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Prefix = app.readString(qs, "prefix", "")
+	input.Category = app.readString(qs, "category", "")
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "name")
+	input.Filters.SortSafelist = []string{"id", "name", "recipe_count", "-id", "-name", "-recipe_count"}
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	ingredients, metadata, err := app.models.Ingredients.GetAll(input.Prefix, input.Category, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"ingredients": ingredients, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) suggestIngredientsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	prefix := app.readString(qs, "prefix", "")
+	limit := app.readInt(qs, "limit", 10, v)
+
+	v.Check(prefix != "", "prefix", "must be provided")
+	v.Check(len(prefix) <= 100, "prefix", "must not be more than 100 bytes long")
+	v.Check(limit > 0, "limit", "must be greater than zero")
+	v.Check(limit <= 50, "limit", "must be a maximum of 50")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	suggestions, err := app.models.Ingredients.Suggest(prefix, limit)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"suggestions": suggestions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// parseIngredientsHandler splits free-text ingredient lines into quantities, units and
+// names, without saving anything.
+func (app *application) parseIngredientsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Lines []string `json:"lines"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(len(input.Lines) > 0, "lines", "must contain at least one line")
+	v.Check(len(input.Lines) <= 100, "lines", "must not contain more than 100 lines")
+	for _, line := range input.Lines {
+		v.Check(len(line) <= 500, "lines", "must not contain lines more than 500 bytes long")
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	lines := make([]ingredientparse.Line, len(input.Lines))
+	for i, line := range input.Lines {
+		lines[i] = ingredientparse.Parse(line)
+	}
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"ingredients": lines}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createIngredientHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name     string   `json:"name"`
+		Category string   `json:"category"`
+		Aliases  []string `json:"aliases"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ingredient := &data.CatalogIngredient{
+		Name:     input.Name,
+		Category: input.Category,
+		Aliases:  input.Aliases,
+	}
+
+	v := validator.New()
+	if data.ValidateCatalogIngredient(v, ingredient); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Ingredients.Insert(ingredient)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateIngredient):
+			v.AddError("name", "an ingredient with this name or alias already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	app.auditAfter(r, "ingredient", ingredient.ID, ingredient)
+
+	err = app.writeJSON(w, r, http.StatusCreated, envelope{"ingredient": ingredient}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateIngredientHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	ingredient, err := app.models.Ingredients.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	app.auditBefore(r, "ingredient", id, ingredient)
+
+	// Fields left out of the request body are not changed.
+	var input struct {
+		Name     *string  `json:"name"`
+		Category *string  `json:"category"`
+		Aliases  []string `json:"aliases"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		ingredient.Name = *input.Name
+	}
+	if input.Category != nil {
+		ingredient.Category = *input.Category
+	}
+	if input.Aliases != nil {
+		ingredient.Aliases = input.Aliases
+	}
+
+	v := validator.New()
+	if data.ValidateCatalogIngredient(v, ingredient); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Ingredients.Update(ingredient)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateIngredient):
+			v.AddError("name", "an ingredient with this name or alias already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	app.auditAfter(r, "ingredient", id, ingredient)
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"ingredient": ingredient}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) mergeIngredientsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		TargetID  int64   `json:"target_id"`
+		SourceIDs []int64 `json:"source_ids"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.TargetID > 0, "target_id", "must be provided")
+	v.Check(len(input.SourceIDs) > 0, "source_ids", "must contain at least one ingredient")
+	v.Check(validator.Unique(input.SourceIDs), "source_ids", "must not contain duplicate values")
+	v.Check(!validator.PermittedValue(input.TargetID, input.SourceIDs...), "source_ids", "must not contain the target")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	ingredient, err := app.models.Ingredients.Merge(input.TargetID, input.SourceIDs)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	app.auditAfter(r, "ingredient", input.TargetID, ingredient)
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"ingredient": ingredient}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listAllIngredientsHandler(w http.ResponseWriter, r *http.Request) {
+	ingredients, err := app.models.Ingredients.ListNames()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"ingredients": ingredients}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
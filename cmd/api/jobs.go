@@ -0,0 +1,80 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// jobRetention is how long a finished job is kept for its status to be polled.
+const jobRetention = 24 * time.Hour
+
+// importJob tracks an import which is running in the background.
+type importJob struct {
+	ID         int64         `json:"id"`
+	Status     string        `json:"status"`
+	DryRun     bool          `json:"dry_run"`
+	Total      int           `json:"total"`
+	Processed  int           `json:"processed"`
+	CreatedAt  time.Time     `json:"created_at"`
+	FinishedAt *time.Time    `json:"finished_at,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Report     *importReport `json:"report,omitempty"`
+}
+
+const (
+	jobQueued    = "queued"
+	jobRunning   = "running"
+	jobCompleted = "completed"
+	jobFailed    = "failed"
+)
+
+// jobRegistry holds the import jobs in memory. Jobs don't survive a restart, which is
+// acceptable since an interrupted import can be resubmitted.
+type jobRegistry struct {
+	mu     sync.Mutex
+	nextID int64
+	jobs   map[int64]*importJob
+}
+
+func newJobRegistry() *jobRegistry {
+	return &jobRegistry{jobs: make(map[int64]*importJob)}
+}
+
+// add registers job, giving it an ID, and drops jobs which finished longer ago than
+// jobRetention.
+func (jr *jobRegistry) add(job *importJob) {
+	jr.mu.Lock()
+	defer jr.mu.Unlock()
+
+	for id, old := range jr.jobs {
+		if old.FinishedAt != nil && time.Since(*old.FinishedAt) > jobRetention {
+			delete(jr.jobs, id)
+		}
+	}
+
+	jr.nextID++
+	job.ID = jr.nextID
+	jr.jobs[job.ID] = job
+}
+
+// update calls fn with the job while holding the lock.
+func (jr *jobRegistry) update(id int64, fn func(job *importJob)) {
+	jr.mu.Lock()
+	defer jr.mu.Unlock()
+
+	if job, ok := jr.jobs[id]; ok {
+		fn(job)
+	}
+}
+
+// get returns a copy of the job, which is safe to read while the job carries on.
+func (jr *jobRegistry) get(id int64) (importJob, bool) {
+	jr.mu.Lock()
+	defer jr.mu.Unlock()
+
+	job, ok := jr.jobs[id]
+	if !ok {
+		return importJob{}, false
+	}
+	return *job, true
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -17,41 +18,47 @@ import (
 
 const version = "1.0.0"
 
-type config struct {
-	//port int
-	env string
-	db  struct {
-		dsn          string
-		maxOpenConns int
-		maxIdleConns int
-		maxIdleTime  string
-	}
-}
+// gitCommit and buildTime are set at link time, for example:
+//
+//	go build -ldflags "-X main.gitCommit=$(git rev-parse HEAD) -X main.buildTime=$(date -u +%FT%TZ)"
+var (
+	gitCommit = "unknown"
+	buildTime = "unknown"
+)
 
 type application struct {
 	config config
 	logger *log.Logger
 	models data.Models
+	jobs   *jobRegistry
 }
 
 func main() {
-	port := os.Getenv("PORT") // Get the port from environment variable
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "export", "import":
+			os.Exit(runBackupCommand(os.Args[1], os.Args[2:]))
+		}
+	}
 
-	logger := log.New(os.Stdout, "", log.Ldate|log.Ltime)
+	cfg, err := loadConfig(os.Args[1:], os.Getenv)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
 
-	if port == "" {
-		logger.Printf("$PORT has not been set, setting port to :4000")
-		port = "4000"
+	if cfg.displayVersion {
+		fmt.Printf("Version:\t%s\n", version)
+		fmt.Printf("Git commit:\t%s\n", gitCommit)
+		fmt.Printf("Build time:\t%s\n", buildTime)
+		os.Exit(0)
 	}
 
-	var cfg config
-	//flag.IntVar(&cfg.port, "port", 4000, "API server port")
-	flag.StringVar(&cfg.env, "env", "development", "Environment (development|staging|production)")
-	flag.IntVar(&cfg.db.maxOpenConns, "db-max-open-conns", 25, "PostgreSQL max open connections")
-	flag.IntVar(&cfg.db.maxIdleConns, "db-max-idle-conns", 25, "PostgreSQL max idle connections")
-	flag.StringVar(&cfg.db.maxIdleTime, "db-max-idle-time", "15m", "PostgreSQL max connection idle time")
-	flag.StringVar(&cfg.db.dsn, "db-dsn", os.Getenv("DB_DSN"), "PostgreSQL DSN")
-	flag.Parse()
+	logger := log.New(os.Stdout, "", log.Ldate|log.Ltime)
+	cfg.printSettings(logger.Printf)
 
 	// Declare an instance of the application struct, containing the config struct and
 	// the logger.
@@ -65,10 +72,14 @@ func main() {
 		config: cfg,
 		logger: logger,
 		models: data.NewModels(DB),
+		jobs:   newJobRegistry(),
 	}
+	app.startTrashPurger()
+	app.startScheduledPublisher()
+	app.startSimilarityRefresher()
 
 	srv := &http.Server{
-		Addr:         fmt.Sprintf(":%s", port),
+		Addr:         fmt.Sprintf(":%d", cfg.port),
 		Handler:      app.routes(),
 		IdleTimeout:  time.Minute,
 		ReadTimeout:  10 * time.Second,
@@ -87,11 +98,7 @@ func openDB(cfg config) (*sql.DB, error) {
 	}
 	DB.SetMaxOpenConns(cfg.db.maxOpenConns)
 	DB.SetMaxIdleConns(cfg.db.maxIdleConns)
-	duration, err := time.ParseDuration(cfg.db.maxIdleTime)
-	if err != nil {
-		return nil, err
-	}
-	DB.SetConnMaxIdleTime(duration)
+	DB.SetConnMaxIdleTime(cfg.db.maxIdleTime)
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -1,10 +1,269 @@
 package main
 
-import "net/http"
+import (
+	"compress/gzip"
+	"crypto/subtle"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"recipe.athif.com/internal/data"
+	"recipe.athif.com/internal/validator"
+)
+
+// corsAllowedHeaders lists the request headers that browsers may send on cross-origin
+// requests. If-Match is needed for conditional updates.
+const corsAllowedHeaders = "Authorization, Content-Type, If-Match"
+
+// corsMaxAge is how long a browser may cache the result of a preflight request.
+const corsMaxAge = 10 * time.Minute
 
 func (app *application) enableCORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*") //"https://searchrecipes.vercel.app")
+		// The response varies depending on the Origin and preflight headers, so make
+		// sure that caches don't serve the same response to different origins.
+		w.Header().Add("Vary", "Origin")
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+
+		origin := r.Header.Get("Origin")
+		if origin != "" && app.isTrustedOrigin(origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+			w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
+
+			// A preflight request is an OPTIONS request carrying an
+			// Access-Control-Request-Method header. Answer it here rather than letting
+			// it reach the router.
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.Header().Set("Access-Control-Allow-Methods", "OPTIONS, GET, POST, PUT, PATCH, DELETE")
+				w.Header().Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(corsMaxAge.Seconds())))
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+		}
 		next.ServeHTTP(w, r)
 	})
 }
+
+// isTrustedOrigin reports whether the origin exactly matches one of the configured
+// trusted origins.
+func (app *application) isTrustedOrigin(origin string) bool {
+	for _, trusted := range app.config.cors.trustedOrigins {
+		if origin == trusted {
+			return true
+		}
+	}
+	return false
+}
+
+// supportedEncodings lists the content-codings compressResponse can apply, in order of
+// preference.
+var supportedEncodings = []string{"br", "gzip"}
+
+// compressResponse compresses response bodies with brotli or gzip, depending on what
+// the client accepts.
+func (app *application) compressResponse(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressResponseWriter{ResponseWriter: w, encoding: encoding}
+		defer cw.close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// negotiateEncoding picks the preferred supported content-coding from an
+// Accept-Encoding header, or returns "" if the response should not be compressed.
+func negotiateEncoding(header string) string {
+	if header == "" {
+		return ""
+	}
+
+	accepted := make(map[string]bool)
+	wildcard := false
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		ok := true
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.TrimSpace(key) == "q" {
+				q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+				ok = err == nil && q > 0
+			}
+		}
+
+		if name == "*" {
+			wildcard = ok
+		} else {
+			accepted[name] = ok
+		}
+	}
+
+	for _, encoding := range supportedEncodings {
+		if ok, listed := accepted[encoding]; ok || (!listed && wildcard) {
+			return encoding
+		}
+	}
+	return ""
+}
+
+// compressResponseWriter compresses the body once the status code is known. Responses
+// without a body are passed through untouched, but their ETag still gets the
+// content-coding suffix so that it matches the one the client saw on the compressed
+// 200 response.
+type compressResponseWriter struct {
+	http.ResponseWriter
+	encoding    string
+	encoder     io.WriteCloser
+	wroteHeader bool
+}
+
+func (cw *compressResponseWriter) WriteHeader(status int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+
+	h := cw.Header()
+	if h.Get("Content-Encoding") == "" && status >= http.StatusOK && status != http.StatusNoContent {
+		if etag := h.Get("ETag"); strings.HasSuffix(etag, `"`) {
+			h.Set("ETag", strings.TrimSuffix(etag, `"`)+"-"+cw.encoding+`"`)
+		}
+		if status != http.StatusNotModified {
+			h.Set("Content-Encoding", cw.encoding)
+			h.Del("Content-Length")
+			switch cw.encoding {
+			case "br":
+				cw.encoder = brotli.NewWriter(cw.ResponseWriter)
+			case "gzip":
+				cw.encoder = gzip.NewWriter(cw.ResponseWriter)
+			}
+		}
+	}
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *compressResponseWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.encoder != nil {
+		return cw.encoder.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+func (cw *compressResponseWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+func (cw *compressResponseWriter) close() {
+	if cw.encoder != nil {
+		cw.encoder.Close()
+	}
+}
+
+// requireAdmin only lets through requests bearing the configured admin token. If no
+// token is configured the admin endpoints are disabled altogether.
+func (app *application) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Authorization")
+
+		token, ok := bearerToken(r)
+		if !ok {
+			app.invalidAuthenticationTokenResponse(w, r)
+			return
+		}
+		if app.config.adminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(app.config.adminToken)) != 1 {
+			app.notPermittedResponse(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+}
+
+// authenticate identifies the author making the request from their bearer token,
+// saving them in the request context. Requests without a token carry on anonymously,
+// while an unknown token is rejected. The admin token is left to requireAdmin.
+func (app *application) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Authorization")
+
+		token, ok := bearerToken(r)
+		if !ok || app.isAdmin(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		v := validator.New()
+		if data.ValidateTokenPlaintext(v, token); !v.Valid() {
+			app.invalidAuthenticationTokenResponse(w, r)
+			return
+		}
+
+		author, err := app.models.Authors.GetForToken(token)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				app.invalidAuthenticationTokenResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+		next.ServeHTTP(w, app.contextSetAuthor(r, author))
+	})
+}
+
+// requireAuthor only lets through requests from a registered author or an admin.
+func (app *application) requireAuthor(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if app.actor(r) == "" {
+			app.authenticationRequiredResponse(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+}
+
+// actor names who made a request, to be recorded against the changes it makes.
+// Requests bearing the admin token are "admin", authors are known by their name, and
+// anonymous requests have no actor.
+func (app *application) actor(r *http.Request) string {
+	if app.isAdmin(r) {
+		return "admin"
+	}
+	if author := app.contextGetAuthor(r); author != nil {
+		return author.Name
+	}
+	return ""
+}
+
+// isAdmin reports whether the request bears the configured admin token. Admins are
+// also the moderators of the publishing workflow.
+func (app *application) isAdmin(r *http.Request) bool {
+	token, ok := bearerToken(r)
+	return ok && app.config.adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(app.config.adminToken)) == 1
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer <token>" header.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}
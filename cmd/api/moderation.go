@@ -0,0 +1,267 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"recipe.athif.com/internal/data"
+	"recipe.athif.com/internal/validator"
+)
+
+// scheduledPublishInterval is how often recipes approved for a later time are checked
+// for publication.
+const scheduledPublishInterval = time.Minute
+
+// ownsRecipe reports whether the request comes from the recipe's author or from an
+// admin.
+func (app *application) ownsRecipe(r *http.Request, recipe *data.Recipe) bool {
+	return app.isAuthor(r, recipe.Author)
+}
+
+// isAuthor reports whether the request comes from the given author or from an admin.
+// Recipes created before the publishing workflow have no author, so only admins own
+// them.
+func (app *application) isAuthor(r *http.Request, author string) bool {
+	return app.isAdmin(r) || (author != "" && author == app.actor(r))
+}
+
+// canViewRecipe reports whether the request may see the recipe. Published recipes are
+// public, and the rest are only visible to those who own them. Handlers respond to
+// recipes the request can't see as if they didn't exist.
+func (app *application) canViewRecipe(r *http.Request, recipe *data.Recipe) bool {
+	return recipe.Status == data.StatusPublished || app.ownsRecipe(r, recipe)
+}
+
+func (app *application) submitRecipeHandler(w http.ResponseWriter, r *http.Request) {
+	app.authorTransition(w, r, data.StatusPendingReview)
+}
+
+func (app *application) withdrawRecipeHandler(w http.ResponseWriter, r *http.Request) {
+	app.authorTransition(w, r, data.StatusDraft)
+}
+
+// authorTransition moves the recipe named in the URL to the given status on behalf of
+// its author.
+func (app *application) authorTransition(w http.ResponseWriter, r *http.Request, to data.Status) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	recipe, err := app.models.Recipes.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !app.canViewRecipe(r, recipe) {
+		app.notFoundResponse(w, r)
+		return
+	}
+	if !app.ownsRecipe(r, recipe) {
+		app.notPermittedResponse(w, r)
+		return
+	}
+	app.setRecipeStatus(w, r, recipe, to, "", time.Time{})
+}
+
+func (app *application) moderationQueueHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.RecipeCriteria
+		data.Filters
+	}
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Status = data.StatusPendingReview
+	input.ExcludeScheduled = true
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "id")
+	input.Filters.SortSafelist = []string{"id", "title", "-id", "-title"}
+	durationFormat := app.readDurationFormat(qs, v)
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	recipes, metadata, err := app.models.Recipes.GetAll(input.RecipeCriteria, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	for _, recipe := range recipes {
+		recipe.SetDurationFormat(durationFormat)
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"recipes": recipes, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// approveRecipeHandler publishes a recipe which is pending review, either at once or,
+// when publish_at is in the future, at that time. A scheduled recipe leaves the
+// moderation queue and can't be approved or rejected again; its author can withdraw
+// it to a draft and submit it anew.
+func (app *application) approveRecipeHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		PublishAt *time.Time `json:"publish_at"`
+	}
+	// The body is optional, since most approvals publish straight away.
+	if r.ContentLength != 0 {
+		err = app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	recipe, err := app.models.Recipes.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var publishAt time.Time
+	if input.PublishAt != nil {
+		publishAt = *input.PublishAt
+	}
+	app.setRecipeStatus(w, r, recipe, data.StatusPublished, "", publishAt)
+}
+
+func (app *application) rejectRecipeHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.Reason != "", "reason", "must be provided")
+	v.Check(len(input.Reason) <= 1000, "reason", "must not be more than 1000 bytes long")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	recipe, err := app.models.Recipes.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.setRecipeStatus(w, r, recipe, data.StatusRejected, input.Reason, time.Time{})
+}
+
+// setRecipeStatus moves the recipe, as loaded by the handler, to a new status and sends
+// it in its new state.
+func (app *application) setRecipeStatus(w http.ResponseWriter, r *http.Request, recipe *data.Recipe, to data.Status, reason string, publishAt time.Time) {
+	id := int64(recipe.ID)
+	app.auditBefore(r, "recipe", id, recipe)
+
+	recipe, err := app.models.Recipes.SetStatus(id, to, reason, publishAt, app.actor(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrInvalidTransition):
+			app.conflictResponse(w, r, err.Error())
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	app.auditAfter(r, "recipe", id, recipe)
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"recipe": recipe}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// scheduledPublisher is the actor recorded against recipes published on schedule, and
+// scheduledPublishRoute the route its audit events are recorded under. It isn't a
+// real route, so the events can't be mistaken for requests.
+const (
+	scheduledPublisher    = "scheduler"
+	scheduledPublishRoute = "scheduler:publish"
+)
+
+// startScheduledPublisher publishes approved recipes once their scheduled time has
+// come, checking once at startup and then every scheduledPublishInterval. Each
+// publication is recorded in the audit log, as if the scheduler had made a request.
+func (app *application) startScheduledPublisher() {
+	publish := func() {
+		app.background(func() {
+			ids, err := app.models.Recipes.PublishDue(scheduledPublisher)
+			if err != nil {
+				app.logger.Printf("publishing scheduled recipes: %v", err)
+				return
+			}
+			for _, id := range ids {
+				event := &data.AuditEvent{
+					Actor:        scheduledPublisher,
+					RequestID:    fmt.Sprintf("%s-%d-%d", scheduledPublisher, time.Now().Unix(), id),
+					IP:           "internal",
+					Method:       http.MethodPost,
+					Route:        scheduledPublishRoute,
+					Status:       http.StatusOK,
+					ResourceType: "recipe",
+					ResourceID:   id,
+					Changes: []data.Change{
+						{Field: "status", Old: data.StatusPendingReview, New: data.StatusPublished},
+					},
+				}
+				if err := app.models.Audit.Insert(event); err != nil {
+					app.logger.Printf("auditing scheduled publication of recipe %d: %v", id, err)
+				}
+			}
+			if len(ids) > 0 {
+				app.logger.Printf("published %d scheduled recipes", len(ids))
+			}
+		})
+	}
+
+	go func() {
+		publish()
+		ticker := time.NewTicker(scheduledPublishInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			publish()
+		}
+	}()
+}
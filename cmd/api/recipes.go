@@ -5,19 +5,31 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"recipe.athif.com/internal/data"
+	"recipe.athif.com/internal/render"
 	"recipe.athif.com/internal/validator"
 )
 
 func (app *application) createRecipeHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Title        string    `json:"title"`
-		Instructions string    `json:"instructions"`
-		PrepTime     data.Mins `json:"preparation_time"`
-		CookTime     data.Mins `json:"cooking_time"`
-		CuisineName  string    `json:"cuisine_name"` // Change this line
-		Difficulty   string    `json:"difficulty"`
+		Title        string            `json:"title"`
+		Instructions string            `json:"instructions"`
+		Steps        []data.Step       `json:"steps"`
+		Tags         []string          `json:"tags"`
+		PrepTime     data.Mins         `json:"prep_time"`
+		CookTime     data.Mins         `json:"cook_time"`
+		CuisineName  string            `json:"cuisine_name"`
+		Difficulty   data.Difficulty   `json:"difficulty"`
+		Ingredients  []data.Ingredient `json:"ingredients"`
+		// IngredientLines are free-text lines such as "2 cups flour", which are parsed
+		// and added to Ingredients.
+		IngredientLines []string `json:"ingredient_lines"`
+		// Status is draft unless the recipe is submitted for review straight away.
+		// Only admins may publish without review.
+		Status data.Status `json:"status"`
 	}
 	err := app.readJSON(w, r, &input)
 	if err != nil {
@@ -25,31 +37,55 @@ func (app *application) createRecipeHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	v := validator.New()
+	input.Ingredients = app.parseIngredientLines(input.Ingredients, input.IngredientLines, v)
+
 	recipe := &data.Recipe{
 		Title:        input.Title,
 		Instructions: input.Instructions,
+		Steps:        input.Steps,
+		Tags:         data.NormalizeTags(input.Tags),
+		Ingredients:  input.Ingredients,
 		PrepTime:     input.PrepTime,
 		CookTime:     input.CookTime,
-		CuisineName:  input.CuisineName, // Change this line
+		CuisineName:  input.CuisineName,
 		Difficulty:   input.Difficulty,
+		Status:       input.Status,
+	}
+	recipe.SyncInstructions()
+	if recipe.Status == "" {
+		recipe.Status = data.StatusDraft
 	}
 
-	v := validator.New()
+	durationFormat := app.readDurationFormat(r.URL.Query(), v)
+	v.Check(validator.PermittedValue(recipe.Status, data.StatusDraft, data.StatusPendingReview, data.StatusPublished), "status", "must be one of draft, pending_review or published")
+	if recipe.Status == data.StatusPublished && !app.isAdmin(r) {
+		v.AddError("status", "only moderators may publish a recipe without review")
+	}
 	if data.ValidateRecipe(v, recipe); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	err = app.models.Recipes.Insert(recipe)
+	err = app.models.Recipes.Insert(recipe, app.actor(r))
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrUnknownCuisine):
+			v.AddError("cuisine_name", "unknown cuisine")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
+	app.auditAfter(r, "recipe", int64(recipe.ID), recipe)
+
+	recipe.SetDurationFormat(durationFormat)
 
 	headers := make(http.Header)
 	headers.Set("Location", fmt.Sprintf("/v1/recipes/%d", recipe.ID))
 
-	err = app.writeJSON(w, http.StatusCreated, envelope{"recipe": recipe}, headers)
+	err = app.writeJSON(w, r, http.StatusCreated, envelope{"recipe": recipe}, headers)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -62,6 +98,21 @@ func (app *application) showRecipeHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// The representation depends on Accept, so caches must key on it too.
+	w.Header().Add("Vary", "Accept")
+
+	v := validator.New()
+	format, ok := app.readRecipeFormat(r, v)
+	if !ok {
+		app.notAcceptableResponse(w, r)
+		return
+	}
+	durationFormat := app.readDurationFormat(r.URL.Query(), v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
 	recipe, err := app.models.Recipes.Get(id)
 	if err != nil {
 		switch {
@@ -72,12 +123,42 @@ func (app *application) showRecipeHandler(w http.ResponseWriter, r *http.Request
 		}
 		return
 	}
+	if !app.canViewRecipe(r, recipe) {
+		app.notFoundResponse(w, r)
+		return
+	}
+	recipe.SetDurationFormat(durationFormat)
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"recipe": recipe}, nil)
+	if format == "json" {
+		err = app.writeCachedJSON(w, r, envelope{"recipe": recipe}, recipe.UpdatedAt)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var body []byte
+	switch format {
+	case "jsonld":
+		body, err = render.JSONLD(recipe)
+	case "markdown":
+		body = render.Markdown(recipe)
+	case "html":
+		body, err = render.HTML(recipe)
+	case "pdf":
+		body, err = render.PDF(recipe)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s.pdf"`, data.Slugify(recipe.Title)))
+	}
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
+		return
 	}
 
+	contentType := recipeFormats[format]
+	if strings.HasPrefix(contentType, "text/") {
+		contentType += "; charset=utf-8"
+	}
+	app.writeCached(w, r, contentType, body, recipe.UpdatedAt)
 }
 
 func (app *application) updateRecipeHandler(w http.ResponseWriter, r *http.Request) {
@@ -97,13 +178,29 @@ func (app *application) updateRecipeHandler(w http.ResponseWriter, r *http.Reque
 		}
 		return
 	}
+	if !app.canViewRecipe(r, recipe) {
+		app.notFoundResponse(w, r)
+		return
+	}
+	if !app.ownsRecipe(r, recipe) {
+		app.notPermittedResponse(w, r)
+		return
+	}
+	app.auditBefore(r, "recipe", id, recipe)
+
 	var input struct {
-		Title        string
-		Instructions string
-		PrepTime     data.Mins
-		CookTime     data.Mins
-		CuisineName  string
-		Difficulty   string
+		Title        string            `json:"title"`
+		Instructions string            `json:"instructions"`
+		Steps        []data.Step       `json:"steps"`
+		Tags         []string          `json:"tags"`
+		PrepTime     data.Mins         `json:"prep_time"`
+		CookTime     data.Mins         `json:"cook_time"`
+		CuisineName  string            `json:"cuisine_name"`
+		Difficulty   data.Difficulty   `json:"difficulty"`
+		Ingredients  []data.Ingredient `json:"ingredients"`
+		// IngredientLines are free-text lines such as "2 cups flour", which are parsed
+		// and added to Ingredients.
+		IngredientLines []string `json:"ingredient_lines"`
 	}
 
 	err = app.readJSON(w, r, &input)
@@ -112,23 +209,47 @@ func (app *application) updateRecipeHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	v := validator.New()
+	input.Ingredients = app.parseIngredientLines(input.Ingredients, input.IngredientLines, v)
+
 	recipe.Title = input.Title
-	recipe.Instructions = input.Instructions
+	recipe.UpdateInstructions(input.Instructions, input.Steps)
+	// Tags and ingredients are left alone when omitted, so clients which don't know
+	// about them can't clear them by accident.
+	if input.Tags != nil {
+		recipe.Tags = data.NormalizeTags(input.Tags)
+	}
+	if input.Ingredients != nil {
+		recipe.Ingredients = input.Ingredients
+	}
 	recipe.PrepTime = input.PrepTime
 	recipe.CookTime = input.CookTime
 	recipe.CuisineName = input.CuisineName
 	recipe.Difficulty = input.Difficulty
-	v := validator.New()
+
+	durationFormat := app.readDurationFormat(r.URL.Query(), v)
 	if data.ValidateRecipe(v, recipe); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
-	err = app.models.Recipes.Update(recipe)
+	// Authors' edits to a published recipe go back through review.
+	err = app.models.Recipes.Update(recipe, app.actor(r), !app.isAdmin(r))
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrUnknownCuisine):
+			v.AddError("cuisine_name", "unknown cuisine")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
-	err = app.writeJSON(w, http.StatusOK, envelope{"recipe": recipe}, nil)
+	app.auditAfter(r, "recipe", id, recipe)
+	recipe.SetDurationFormat(durationFormat)
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"recipe": recipe}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -141,6 +262,26 @@ func (app *application) deleteRecipeHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	recipe, err := app.models.Recipes.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !app.canViewRecipe(r, recipe) {
+		app.notFoundResponse(w, r)
+		return
+	}
+	if !app.ownsRecipe(r, recipe) {
+		app.notPermittedResponse(w, r)
+		return
+	}
+	app.auditBefore(r, "recipe", id, recipe)
+
 	err = app.models.Recipes.Delete(id)
 	if err != nil {
 		switch {
@@ -152,7 +293,7 @@ func (app *application) deleteRecipeHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 	// Return a 200 OK status code along with a success message.
-	err = app.writeJSON(w, http.StatusOK, envelope{"message": "recipe successfully deleted"}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"message": "recipe moved to the trash"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -161,8 +302,7 @@ func (app *application) deleteRecipeHandler(w http.ResponseWriter, r *http.Reque
 func (app *application) listRecipeHandler(w http.ResponseWriter, r *http.Request) {
 
 	var input struct {
-		Title     string
-		CuisineID int
+		data.RecipeCriteria
 		data.Filters
 	}
 	v := validator.New()
@@ -171,23 +311,36 @@ func (app *application) listRecipeHandler(w http.ResponseWriter, r *http.Request
 
 	input.Title = app.readString(qs, "title", "")
 	input.CuisineID = app.readInt(qs, "cuisineid", 0, v)
+	input.Cuisine = app.readString(qs, "cuisine", "")
+	input.Tags = data.NormalizeTags(app.readCSV(qs, "tags", nil))
+	tagsMatch := app.readString(qs, "tags_match", "all")
+	v.Check(validator.PermittedValue(tagsMatch, "all", "any"), "tags_match", "must be all or any")
+	input.AnyTag = tagsMatch == "any"
+	app.readStatusCriteria(r, qs, &input.RecipeCriteria, v)
+	input.RecipeLimits = app.readRecipeLimits(qs, v)
+	durationFormat := app.readDurationFormat(qs, v)
 
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	//input.Filters.PageSize = app.readInt(qs, "pagesize", 20, v)
 
 	input.Filters.Sort = app.readString(qs, "sort", "id")
-	input.Filters.SortSafelist = []string{"id", "title", "difficulty", "cuisinename", "-id", "-title", "-difficulty", "-cuisinename"}
+	input.Filters.SortSafelist = []string{"id", "title", "difficulty", "cuisinename", "total_time", "prep_time", "cook_time", "-id", "-title", "-difficulty", "-cuisinename", "-total_time", "-prep_time", "-cook_time"}
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	recipes, metadata, err := app.models.Recipes.GetAll(input.Title, input.CuisineID, input.Filters)
+	recipes, metadata, err := app.models.Recipes.GetAll(input.RecipeCriteria, input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
-	err = app.writeJSON(w, http.StatusOK, envelope{"recipes": recipes, "metadata": metadata}, nil)
+	for _, recipe := range recipes {
+		recipe.SetDurationFormat(durationFormat)
+	}
+	// Last-Modified is deliberately left out of listings: deleting a recipe changes the
+	// list without advancing any updated_at, so only the ETag can be relied on.
+	err = app.writeCachedJSON(w, r, envelope{"recipes": recipes, "metadata": metadata}, time.Time{})
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -195,39 +348,36 @@ func (app *application) listRecipeHandler(w http.ResponseWriter, r *http.Request
 
 func (app *application) searchRecipesHandler(w http.ResponseWriter, r *http.Request) {
 	// Create a new validator instance
-	//v := validator.New()
+	v := validator.New()
 	var input struct {
 		Ingredients []string
+		data.RecipeLimits
 	}
 
 	qs := r.URL.Query()
 	input.Ingredients = app.readCSV(qs, "ingredients", []string{}) // Use readCSV here
+	input.RecipeLimits = app.readRecipeLimits(qs, v)
+	durationFormat := app.readDurationFormat(qs, v)
+
+	v.Check(len(input.Ingredients) > 0, "ingredients", "must contain at least one ingredient")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
 
 	// Call the Search method on the Recipes model.
-	recipes, err := app.models.Recipes.Search(input.Ingredients)
+	recipes, err := app.models.Recipes.Search(input.Ingredients, input.RecipeLimits)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
+	for _, recipe := range recipes {
+		recipe.SetDurationFormat(durationFormat)
+	}
 
 	// Write the returned recipes to the response.
-	err = app.writeJSON(w, http.StatusOK, envelope{"recipes": recipes}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"recipes": recipes}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
-
-func (app *application) listAllIngredientsHandler(w http.ResponseWriter, r *http.Request) {
-    // Call the ListAllIngredients method on the RecipeModel.
-    ingredients, err := app.models.Recipes.ListAllIngredients()
-    if err != nil {
-        app.serverErrorResponse(w, r, err)
-        return
-    }
-
-    // Write the returned ingredients to the response.
-    err = app.writeJSON(w, http.StatusOK, envelope{"ingredients": ingredients}, nil)
-    if err != nil {
-        app.serverErrorResponse(w, r, err)
-    }
-}
\ No newline at end of file
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+
+	"recipe.athif.com/internal/validator"
+)
+
+// maxRecommendations is the most recommendations a request may ask for.
+const maxRecommendations = 50
+
+// listRecommendationsHandler recommends published recipes to the caller from the
+// recipes they saved and rated highly. Each comes with an explanation of why it was
+// chosen. Callers who haven't liked anything yet get an empty list.
+func (app *application) listRecommendationsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	limit := app.readInt(qs, "limit", 10, v)
+	durationFormat := app.readDurationFormat(qs, v)
+
+	v.Check(limit > 0, "limit", "must be greater than zero")
+	v.Check(limit <= maxRecommendations, "limit", "must be a maximum of 50")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	recommendations, err := app.models.Recipes.Recommend(app.actor(r), limit)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	for _, recommendation := range recommendations {
+		recommendation.Recipe.SetDurationFormat(durationFormat)
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"recommendations": recommendations}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
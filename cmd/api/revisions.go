@@ -0,0 +1,206 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"recipe.athif.com/internal/data"
+	"recipe.athif.com/internal/validator"
+)
+
+func (app *application) listRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	if !app.recipeVisible(w, r, id) {
+		return
+	}
+
+	var input struct {
+		data.Filters
+	}
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "-revision")
+	input.Filters.SortSafelist = []string{"revision", "-revision"}
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	revisions, metadata, err := app.models.Recipes.GetRevisions(id, input.Filters)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"revisions": revisions, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	rev, err := app.readRevisionParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	if !app.recipeVisible(w, r, id) {
+		return
+	}
+
+	revision, err := app.models.Recipes.GetRevision(id, rev)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"revision": revision}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// diffRevisionsHandler lists the field-level changes from the revision given by the
+// from parameter, which defaults to the one before, to the revision in the URL.
+func (app *application) diffRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	rev, err := app.readRevisionParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	if !app.recipeVisible(w, r, id) {
+		return
+	}
+
+	v := validator.New()
+	from := app.readInt(r.URL.Query(), "from", rev-1, v)
+	v.Check(from > 0, "from", "must be greater than zero")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	var revisions [2]*data.Revision
+	for i, number := range []int{from, rev} {
+		revisions[i], err = app.models.Recipes.GetRevision(id, number)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+	}
+
+	diff := envelope{
+		"from":    from,
+		"to":      rev,
+		"changes": data.DiffSnapshots(revisions[0].Recipe, revisions[1].Recipe),
+	}
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"diff": diff}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) restoreRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	rev, err := app.readRevisionParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	before, err := app.models.Recipes.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !app.canViewRecipe(r, before) {
+		app.notFoundResponse(w, r)
+		return
+	}
+	if !app.ownsRecipe(r, before) {
+		app.notPermittedResponse(w, r)
+		return
+	}
+	app.auditBefore(r, "recipe", id, before)
+
+	recipe, err := app.models.Recipes.RestoreRevision(id, rev, app.actor(r), !app.isAdmin(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrUnknownCuisine):
+			app.conflictResponse(w, r, "the revision's cuisine no longer exists")
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	app.auditAfter(r, "recipe", id, recipe)
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"recipe": recipe}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// recipeVisible reports whether the recipe exists and the request may see it, sending
+// the error response when it can't.
+func (app *application) recipeVisible(w http.ResponseWriter, r *http.Request, id int64) bool {
+	recipe, err := app.models.Recipes.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return false
+	}
+	if !app.canViewRecipe(r, recipe) {
+		app.notFoundResponse(w, r)
+		return false
+	}
+	return true
+}
@@ -7,16 +7,75 @@ import (
 )
 
 func (app *application) routes() http.Handler {
-    router := httprouter.New()
-    router.NotFound = http.HandlerFunc(app.notFoundResponse)
-    router.MethodNotAllowed = http.HandlerFunc(app.methodNotAllowedResponse)
-    router.HandlerFunc(http.MethodGet, "/v1/healthcheck", app.healthcheckHandler)
-    router.HandlerFunc(http.MethodGet, "/v1/recipes", app.listRecipeHandler)
-    router.HandlerFunc(http.MethodPost, "/v1/recipes", app.createRecipeHandler)
-    router.HandlerFunc(http.MethodGet, "/v1/search", app.searchRecipesHandler)
-    router.HandlerFunc(http.MethodGet, "/v1/listingredients", app.listAllIngredientsHandler)
-    router.HandlerFunc(http.MethodGet, "/v1/recipes/:id", app.showRecipeHandler)
-    router.HandlerFunc(http.MethodPut, "/v1/recipes/:id", app.updateRecipeHandler)
-    router.HandlerFunc(http.MethodDelete, "/v1/recipes/:id", app.deleteRecipeHandler)
-    return app.enableCORS(router)
-}
\ No newline at end of file
+	return app.compressResponse(app.requestID(app.enableCORS(app.authenticate(app.auditLog(app.router())))))
+}
+
+// dispatchedRoutes are the routes with a fixed segment where another route has a
+// wildcard. httprouter can't register a static "import" segment beside the :id of
+// POST /v1/recipes/:id/fork and the other recipe actions, and panics if asked to, so
+// these are registered as the wildcard route and dispatched by its handler.
+var dispatchedRoutes = map[string]bool{
+	"POST /v1/recipes/import": true,
+}
+
+// router registers every endpoint. It is kept apart from the middleware in routes so
+// that the routes can be checked against the OpenAPI document.
+func (app *application) router() *httprouter.Router {
+	router := httprouter.New()
+	router.NotFound = http.HandlerFunc(app.notFoundResponse)
+	router.MethodNotAllowed = http.HandlerFunc(app.methodNotAllowedResponse)
+	router.HandlerFunc(http.MethodGet, "/v1/healthcheck", app.healthzHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/healthz", app.healthzHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/readyz", app.readyzHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/recipes", app.listRecipeHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/recipes", app.requireAuthor(app.createRecipeHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/search", app.searchRecipesHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/listingredients", app.listAllIngredientsHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/recipes/:id", app.showRecipeHandler)
+	router.HandlerFunc(http.MethodPut, "/v1/recipes/:id", app.requireAuthor(app.updateRecipeHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/recipes/:id", app.requireAuthor(app.deleteRecipeHandler))
+	// POST /v1/recipes/import is served by recipePostHandler; see dispatchedRoutes.
+	router.HandlerFunc(http.MethodPost, "/v1/recipes/:id", app.recipePostHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/recipes/:id/restore", app.requireAuthor(app.restoreRecipeHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/recipes/:id/submit", app.requireAuthor(app.submitRecipeHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/recipes/:id/withdraw", app.requireAuthor(app.withdrawRecipeHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/recipes/:id/fork", app.requireAuthor(app.forkRecipeHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/recipes/:id/variations", app.listVariationsHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/recipes/:id/lineage", app.showLineageHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/recipes/:id/similar", app.similarRecipesHandler)
+	router.HandlerFunc(http.MethodPut, "/v1/recipes/:id/favorite", app.requireAuthor(app.addFavoriteHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/recipes/:id/favorite", app.requireAuthor(app.removeFavoriteHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/recipes/:id/rating", app.requireAuthor(app.rateRecipeHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/recipes/:id/rating", app.requireAuthor(app.removeRatingHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/recipes/:id/diff", app.diffRecipeHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/recipes/:id/revisions", app.listRevisionsHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/recipes/:id/revisions/:rev", app.showRevisionHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/recipes/:id/revisions/:rev/diff", app.diffRevisionsHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/recipes/:id/revisions/:rev/restore", app.requireAuthor(app.restoreRevisionHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/trash", app.requireAuthor(app.listTrashHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/moderation/queue", app.requireAdmin(app.moderationQueueHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/moderation/recipes/:id/approve", app.requireAdmin(app.approveRecipeHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/moderation/recipes/:id/reject", app.requireAdmin(app.rejectRecipeHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/cuisines", app.listCuisinesHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/cuisines", app.requireAdmin(app.createCuisineHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/cuisines/:id", app.showCuisineHandler)
+	router.HandlerFunc(http.MethodPut, "/v1/cuisines/:id", app.requireAdmin(app.updateCuisineHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/cuisines/:id", app.requireAdmin(app.deleteCuisineHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/ingredients", app.listIngredientsHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/ingredients/suggest", app.suggestIngredientsHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/ingredients", app.requireAdmin(app.createIngredientHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/ingredients/parse", app.parseIngredientsHandler)
+	router.HandlerFunc(http.MethodPatch, "/v1/ingredients/:id", app.requireAdmin(app.updateIngredientHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/admin/ingredients/merge", app.requireAdmin(app.mergeIngredientsHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/admin/audit", app.requireAdmin(app.listAuditEventsHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/tags", app.listTagsHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/authors", app.registerAuthorHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/me", app.showCurrentAuthorHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/me/recommendations", app.requireAuthor(app.listRecommendationsHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/imports/:id", app.requireAdmin(app.showImportHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/imports/schemaorg", app.requireAdmin(app.importSchemaOrgHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/openapi.json", app.openAPIHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/docs", app.docsHandler)
+	return router
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// specParamRX matches a path parameter in the OpenAPI document, such as "{id}".
+var specParamRX = regexp.MustCompile(`\{(\w+)\}`)
+
+// TestRoutesMatchOpenAPI checks that every operation in the OpenAPI document is
+// routed, with the same path parameters, and that every route is documented. A
+// wildcard route which only dispatches documented fixed paths counts as documented.
+func TestRoutesMatchOpenAPI(t *testing.T) {
+	documented := specOperations(t)
+	registered := registeredRoutes(t)
+
+	app := &application{}
+	router := app.router()
+	dispatching := map[string]bool{}
+	for op := range dispatchedRoutes {
+		if _, ok := documented[op]; !ok {
+			t.Errorf("%s is dispatched but not documented", op)
+		}
+		method, path, _ := strings.Cut(op, " ")
+		_, params, _ := router.Lookup(method, path)
+		dispatching[method+" "+routePattern(path, params)] = true
+	}
+	for op, params := range documented {
+		method, path, _ := strings.Cut(op, " ")
+		handle, got, _ := router.Lookup(method, specParamRX.ReplaceAllString(path, "1"))
+		if handle == nil {
+			t.Errorf("%s is documented but not routed", op)
+			continue
+		}
+		if dispatchedRoutes[op] {
+			continue
+		}
+		keys := make([]string, len(got))
+		for i, param := range got {
+			keys[i] = param.Key
+		}
+		if strings.Join(keys, ",") != strings.Join(params, ",") {
+			t.Errorf("%s is routed with parameters %v, but documented with %v", op, keys, params)
+		}
+	}
+
+	for _, op := range registered {
+		if dispatching[op] {
+			continue
+		}
+		method, path, _ := strings.Cut(op, " ")
+		path = regexp.MustCompile(`:(\w+)`).ReplaceAllString(path, "{$1}")
+		if _, ok := documented[method+" "+path]; !ok {
+			t.Errorf("%s is routed but not documented", op)
+		}
+	}
+}
+
+// specOperations returns the operations in the OpenAPI document as "METHOD /path",
+// each with the names of its path parameters in order.
+func specOperations(t *testing.T) map[string][]string {
+	t.Helper()
+
+	b, err := docsFS.ReadFile("docs/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(b, &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	methods := map[string]bool{
+		http.MethodGet: true, http.MethodPost: true, http.MethodPut: true,
+		http.MethodPatch: true, http.MethodDelete: true,
+	}
+	operations := map[string][]string{}
+	for path, item := range spec.Paths {
+		var params []string
+		for _, m := range specParamRX.FindAllStringSubmatch(path, -1) {
+			params = append(params, m[1])
+		}
+		for method := range item {
+			if method = strings.ToUpper(method); methods[method] {
+				operations[method+" "+path] = params
+			}
+		}
+	}
+	if len(operations) == 0 {
+		t.Fatal("the OpenAPI document has no operations")
+	}
+	return operations
+}
+
+// registeredRoutes returns the routes registered in routes.go as "METHOD /path".
+// httprouter can't list its routes, so they are read from the calls to
+// router.HandlerFunc.
+func registeredRoutes(t *testing.T) []string {
+	t.Helper()
+
+	file, err := parser.ParseFile(token.NewFileSet(), "routes.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var routes []string
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) < 2 {
+			return true
+		}
+		fun, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || fun.Sel.Name != "HandlerFunc" {
+			return true
+		}
+		method, ok := call.Args[0].(*ast.SelectorExpr)
+		if !ok {
+			t.Errorf("can't read the method of the route at offset %d", call.Pos())
+			return true
+		}
+		lit, ok := call.Args[1].(*ast.BasicLit)
+		if !ok {
+			t.Errorf("can't read the path of the route at offset %d", call.Pos())
+			return true
+		}
+		path, err := strconv.Unquote(lit.Value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		routes = append(routes, strings.ToUpper(strings.TrimPrefix(method.Sel.Name, "Method"))+" "+path)
+		return true
+	})
+	if len(routes) == 0 {
+		t.Fatal("found no routes in routes.go")
+	}
+	sort.Strings(routes)
+	return routes
+}
+
+// TestRecipeImportRoute checks that POST /v1/recipes/import reaches the import handler,
+// which needs an admin, while other recipes still don't accept a POST.
+func TestRecipeImportRoute(t *testing.T) {
+	app := &application{}
+	router := app.router()
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/v1/recipes/import", http.StatusUnauthorized},
+		{"/v1/recipes/1", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader("{}")))
+		if w.Code != tt.want {
+			t.Errorf("POST %s = %d, want %d", tt.path, w.Code, tt.want)
+		}
+	}
+}
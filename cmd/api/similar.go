@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"recipe.athif.com/internal/similarity"
+	"recipe.athif.com/internal/validator"
+)
+
+const (
+	// maxSimilarRecipes is how many similar recipes are kept for each recipe, and so
+	// the most a request may ask for.
+	maxSimilarRecipes = 50
+	// similarityRebuildInterval is how often every score is recomputed, which picks up
+	// the drift in ingredient weights that incremental refreshes leave behind.
+	similarityRebuildInterval = 24 * time.Hour
+	// similarityRefreshInterval is how often the scores of recently changed recipes are
+	// recomputed.
+	similarityRefreshInterval = time.Minute
+	// similarityRefreshOverlap is how far each refresh looks back before the previous
+	// one, so that changes committed while it ran aren't missed.
+	similarityRefreshOverlap = 30 * time.Second
+)
+
+// similarRecipesHandler lists the published recipes most like the one named in the URL,
+// best first, as last computed by the similarity refresher.
+func (app *application) similarRecipesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	if !app.recipeVisible(w, r, id) {
+		return
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	limit := app.readInt(qs, "limit", 10, v)
+	durationFormat := app.readDurationFormat(qs, v)
+
+	v.Check(limit > 0, "limit", "must be greater than zero")
+	v.Check(limit <= maxSimilarRecipes, "limit", "must be a maximum of 50")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	similar, err := app.models.Recipes.GetSimilar(id, limit)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	for _, s := range similar {
+		s.Recipe.SetDurationFormat(durationFormat)
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"similar": similar}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// startSimilarityRefresher keeps the stored similarity scores up to date. It rebuilds
+// them all at startup and every similarityRebuildInterval, and in between recomputes
+// the scores of recipes which changed every similarityRefreshInterval. A run which is
+// still going when the next is due causes that one to be skipped.
+func (app *application) startSimilarityRefresher() {
+	var mu sync.Mutex
+	var since time.Time
+
+	refresh := func(full bool) {
+		app.background(func() {
+			if !mu.TryLock() {
+				return
+			}
+			defer mu.Unlock()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+			defer cancel()
+
+			ids, now, err := app.models.Similarity.ChangedSince(ctx, since)
+			if err != nil {
+				app.logger.Printf("refreshing recipe similarity: %v", err)
+				return
+			}
+			if !full && len(ids) == 0 {
+				since = now.Add(-similarityRefreshOverlap)
+				return
+			}
+
+			recipes, err := app.models.Similarity.Features(ctx)
+			if err != nil {
+				app.logger.Printf("refreshing recipe similarity: %v", err)
+				return
+			}
+			index := similarity.NewIndex(recipes)
+
+			if full {
+				err = app.models.Similarity.ReplaceAll(ctx, index.All(maxSimilarRecipes))
+			} else {
+				err = app.models.Similarity.Replace(ctx, ids, index.Refresh(ids, maxSimilarRecipes))
+			}
+			if err != nil {
+				app.logger.Printf("refreshing recipe similarity: %v", err)
+				return
+			}
+			since = now.Add(-similarityRefreshOverlap)
+
+			if full {
+				app.logger.Printf("rebuilt similarity scores for %d recipes", index.Len())
+			}
+		})
+	}
+
+	go func() {
+		refresh(true)
+		rebuild := time.NewTicker(similarityRebuildInterval)
+		defer rebuild.Stop()
+		ticker := time.NewTicker(similarityRefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-rebuild.C:
+				refresh(true)
+			case <-ticker.C:
+				refresh(false)
+			}
+		}
+	}()
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"net/http"
+
+	"recipe.athif.com/internal/data"
+	"recipe.athif.com/internal/validator"
+)
+
+func (app *application) listTagsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	category := app.readString(r.URL.Query(), "category", "")
+	v.Check(category == "" || validator.PermittedValue(category, data.TagCategories...), "category", "invalid category")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	tags, err := app.models.Tags.GetAll(category)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"tags": tags}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"recipe.athif.com/internal/data"
+	"recipe.athif.com/internal/validator"
+)
+
+// trashPurgeInterval is how often recipes past the trash retention period are purged.
+const trashPurgeInterval = time.Hour
+
+// trashOwner returns the author whose trash the request may see, or an empty string
+// for admins, who see everyone's.
+func (app *application) trashOwner(r *http.Request) string {
+	if app.isAdmin(r) {
+		return ""
+	}
+	return app.actor(r)
+}
+
+// listTrashHandler lists the caller's trashed recipes, or every trashed recipe for
+// admins.
+func (app *application) listTrashHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.Filters
+	}
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "-deleted_at")
+	input.Filters.SortSafelist = []string{"id", "title", "deleted_at", "-id", "-title", "-deleted_at"}
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	recipes, metadata, err := app.models.Recipes.GetTrash(app.trashOwner(r), input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if retention := app.config.trash.retention; retention > 0 {
+		for _, recipe := range recipes {
+			purgeAt := recipe.DeletedAt.Add(retention)
+			recipe.PurgeAt = &purgeAt
+		}
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"recipes": recipes, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// restoreRecipeHandler takes one of the caller's recipes out of the trash. Recipes in
+// someone else's trash are treated as if they didn't exist.
+func (app *application) restoreRecipeHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	recipe, err := app.models.Recipes.Restore(id, app.trashOwner(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	app.auditAfter(r, "recipe", id, recipe)
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"recipe": recipe}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// startTrashPurger purges recipes which have been in the trash for longer than the
+// retention period, once at startup and then every trashPurgeInterval. It does nothing
+// when the retention period is zero.
+func (app *application) startTrashPurger() {
+	retention := app.config.trash.retention
+	if retention <= 0 {
+		return
+	}
+
+	purge := func() {
+		app.background(func() {
+			n, err := app.models.Recipes.Purge(retention)
+			if err != nil {
+				app.logger.Printf("purging trash: %v", err)
+				return
+			}
+			if n > 0 {
+				app.logger.Printf("purged %d recipes from the trash", n)
+			}
+		})
+	}
+
+	go func() {
+		purge()
+		ticker := time.NewTicker(trashPurgeInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			purge()
+		}
+	}()
+}
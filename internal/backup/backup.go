@@ -0,0 +1,367 @@
+// Package backup reads and writes the versioned NDJSON format used to move a recipe
+// catalog between databases.
+//
+// A backup is one JSON record per line. The first is a header naming the format and
+// version, then come the cuisines, the ingredient catalog and the recipes, and the last
+// is a trailer with the record counts and a SHA-256 checksum of every line between the
+// header and the trailer. Records carry no database IDs and are written in a fixed
+// order, so the checksum depends only on the catalog: exporting a database that a backup
+// was just restored into gives the same checksum as the backup.
+package backup
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+
+	"recipe.athif.com/internal/data"
+	"recipe.athif.com/internal/validator"
+)
+
+const (
+	Format  = "recipe-backup"
+	Version = 1
+)
+
+// Record types.
+const (
+	TypeHeader     = "header"
+	TypeCuisine    = "cuisine"
+	TypeIngredient = "ingredient"
+	TypeRecipe     = "recipe"
+	TypeTrailer    = "trailer"
+)
+
+// maxLineSize bounds a single record, which is far more than any real recipe needs.
+const maxLineSize = 16 << 20
+
+// pageSize is how many recipes are read from the database at a time when exporting.
+const pageSize = 100
+
+// Record is one line of a backup.
+type Record struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+type Header struct {
+	Format        string    `json:"format"`
+	Version       int       `json:"version"`
+	SchemaVersion uint      `json:"schema_version"`
+	ExportedAt    time.Time `json:"exported_at"`
+}
+
+type Trailer struct {
+	Cuisines    int    `json:"cuisines"`
+	Ingredients int    `json:"ingredients"`
+	Recipes     int    `json:"recipes"`
+	Checksum    string `json:"checksum"`
+}
+
+// writer writes records, hashing those which count towards the checksum.
+type writer struct {
+	w   *bufio.Writer
+	sum hash.Hash
+}
+
+func (w *writer) write(recordType string, v any, hashed bool) error {
+	js, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(Record{Type: recordType, Data: js})
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	if hashed {
+		w.sum.Write(line)
+	}
+	_, err = w.w.Write(line)
+	return err
+}
+
+// Export writes the whole catalog to w and returns the trailer it wrote.
+// schemaVersion is the migration version of the database, recorded so that a backup
+// is never restored into an older schema.
+func Export(ctx context.Context, m data.BackupModel, w io.Writer, schemaVersion uint) (Trailer, error) {
+	bw := &writer{w: bufio.NewWriter(w), sum: sha256.New()}
+	var trailer Trailer
+
+	header := Header{Format: Format, Version: Version, SchemaVersion: schemaVersion, ExportedAt: time.Now().UTC().Truncate(time.Second)}
+	err := bw.write(TypeHeader, header, false)
+	if err != nil {
+		return trailer, err
+	}
+
+	cuisines, err := m.Cuisines(ctx)
+	if err != nil {
+		return trailer, err
+	}
+	for _, cuisine := range cuisines {
+		if err := bw.write(TypeCuisine, cuisine, true); err != nil {
+			return trailer, err
+		}
+	}
+	trailer.Cuisines = len(cuisines)
+
+	ingredients, err := m.Ingredients(ctx)
+	if err != nil {
+		return trailer, err
+	}
+	for _, ingredient := range ingredients {
+		if err := bw.write(TypeIngredient, ingredient, true); err != nil {
+			return trailer, err
+		}
+	}
+	trailer.Ingredients = len(ingredients)
+
+	var after string
+	for {
+		recipes, err := m.Recipes(ctx, after, pageSize)
+		if err != nil {
+			return trailer, err
+		}
+		for _, recipe := range recipes {
+			if err := bw.write(TypeRecipe, recipe, true); err != nil {
+				return trailer, err
+			}
+		}
+		trailer.Recipes += len(recipes)
+		if len(recipes) < pageSize {
+			break
+		}
+		after = recipes[len(recipes)-1].UID
+	}
+
+	trailer.Checksum = "sha256:" + hex.EncodeToString(bw.sum.Sum(nil))
+	err = bw.write(TypeTrailer, trailer, false)
+	if err != nil {
+		return trailer, err
+	}
+	return trailer, bw.w.Flush()
+}
+
+// ImportOptions controls how a backup is restored.
+type ImportOptions struct {
+	// BatchSize is the number of recipes restored per transaction. When it is zero the
+	// whole backup is restored in one transaction, which is committed only once the
+	// trailer's checksum has been verified.
+	BatchSize int
+	// ResumeAfter skips recipes whose uid sorts at or before it, so that an import
+	// interrupted part way through can carry on from the last committed batch.
+	ResumeAfter string
+	// Logf, if set, reports each committed batch.
+	Logf func(format string, args ...any)
+}
+
+// Summary reports what an import did.
+type Summary struct {
+	Trailer
+	Created int
+	Updated int
+	Skipped int
+}
+
+// LineError is returned for a record which couldn't be read or restored.
+type LineError struct {
+	Line int
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	ErrTruncated        = errors.New("backup is truncated: no trailer")
+)
+
+// Import restores a backup written by Export. dbSchemaVersion is the migration
+// version of the target database, which must be at least that of the exported one.
+//
+// In batch mode a failure leaves the earlier batches committed. Since restoring is
+// idempotent, the import can simply be run again, or resumed with ResumeAfter.
+func Import(ctx context.Context, m data.BackupModel, r io.Reader, dbSchemaVersion uint, opts ImportOptions) (Summary, error) {
+	var summary Summary
+	logf := opts.Logf
+	if logf == nil {
+		logf = func(string, ...any) {}
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	sum := sha256.New()
+	lineNo := 0
+
+	next := func() (*Record, []byte, error) {
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return nil, nil, &LineError{lineNo + 1, err}
+			}
+			return nil, nil, nil
+		}
+		lineNo++
+		line := scanner.Bytes()
+		var record Record
+		err := json.Unmarshal(line, &record)
+		if err != nil {
+			return nil, nil, &LineError{lineNo, err}
+		}
+		return &record, line, nil
+	}
+
+	record, _, err := next()
+	switch {
+	case err != nil:
+		return summary, err
+	case record == nil || record.Type != TypeHeader:
+		return summary, &LineError{1, errors.New("missing header")}
+	}
+	var header Header
+	if err := json.Unmarshal(record.Data, &header); err != nil {
+		return summary, &LineError{1, err}
+	}
+	switch {
+	case header.Format != Format:
+		return summary, &LineError{1, fmt.Errorf("unknown format %q", header.Format)}
+	case header.Version < 1 || header.Version > Version:
+		return summary, &LineError{1, fmt.Errorf("unsupported version %d (this build reads up to %d)", header.Version, Version)}
+	case header.SchemaVersion > dbSchemaVersion:
+		return summary, fmt.Errorf("backup needs schema version %d but the database is at %d: run the migrations first", header.SchemaVersion, dbSchemaVersion)
+	}
+
+	restorer, err := m.BeginRestore(ctx)
+	if err != nil {
+		return summary, err
+	}
+	defer func() {
+		restorer.Rollback()
+	}()
+	inBatch := 0
+	var lastUID string
+	// Forks are linked to their parents at the end, since a parent may come later in
+	// the backup, or have been restored by an earlier run when resuming.
+	parents := map[string]string{}
+
+	for {
+		record, line, err := next()
+		if err != nil {
+			return summary, err
+		}
+		if record == nil {
+			return summary, ErrTruncated
+		}
+
+		if record.Type == TypeTrailer {
+			var trailer Trailer
+			if err := json.Unmarshal(record.Data, &trailer); err != nil {
+				return summary, &LineError{lineNo, err}
+			}
+			checksum := "sha256:" + hex.EncodeToString(sum.Sum(nil))
+			switch {
+			case trailer.Checksum != checksum:
+				return summary, fmt.Errorf("%w: trailer has %s, records hash to %s", ErrChecksumMismatch, trailer.Checksum, checksum)
+			case trailer.Cuisines != summary.Cuisines, trailer.Ingredients != summary.Ingredients, trailer.Recipes != summary.Recipes:
+				return summary, &LineError{lineNo, errors.New("record counts don't match the trailer")}
+			}
+			if scanner.Scan() {
+				return summary, &LineError{lineNo + 1, errors.New("unexpected data after the trailer")}
+			}
+			summary.Checksum = checksum
+			break
+		}
+
+		sum.Write(line)
+		sum.Write([]byte{'\n'})
+
+		switch record.Type {
+		case TypeCuisine:
+			var cuisine data.BackupCuisine
+			err = json.Unmarshal(record.Data, &cuisine)
+			if err == nil {
+				err = restorer.Cuisine(cuisine)
+			}
+			summary.Cuisines++
+
+		case TypeIngredient:
+			var ingredient data.BackupIngredient
+			err = json.Unmarshal(record.Data, &ingredient)
+			if err == nil {
+				err = restorer.Ingredient(ingredient)
+			}
+			summary.Ingredients++
+
+		case TypeRecipe:
+			summary.Recipes++
+			var recipe data.BackupRecipe
+			err = json.Unmarshal(record.Data, &recipe)
+			if err != nil {
+				break
+			}
+			if recipe.ParentUID != "" {
+				parents[recipe.UID] = recipe.ParentUID
+			}
+			if opts.ResumeAfter != "" && recipe.UID <= opts.ResumeAfter {
+				summary.Skipped++
+				continue
+			}
+			v := validator.New()
+			if data.ValidateBackupRecipe(v, &recipe); !v.Valid() {
+				err = fmt.Errorf("invalid recipe %s: %v", recipe.UID, v.Errors)
+				break
+			}
+			var created bool
+			created, err = restorer.Recipe(&recipe)
+			if err != nil {
+				err = fmt.Errorf("recipe %s: %w", recipe.UID, err)
+				break
+			}
+			if created {
+				summary.Created++
+			} else {
+				summary.Updated++
+			}
+			lastUID = recipe.UID
+			inBatch++
+
+		default:
+			err = fmt.Errorf("unknown record type %q", record.Type)
+		}
+		if err != nil {
+			return summary, &LineError{lineNo, err}
+		}
+
+		if opts.BatchSize > 0 && inBatch >= opts.BatchSize {
+			if err := restorer.Commit(); err != nil {
+				return summary, err
+			}
+			logf("committed %d recipes, through uid %s", summary.Created+summary.Updated, lastUID)
+			restorer, err = m.BeginRestore(ctx)
+			if err != nil {
+				return summary, err
+			}
+			inBatch = 0
+		}
+	}
+
+	if err := restorer.Commit(); err != nil {
+		return summary, err
+	}
+	if len(parents) > 0 {
+		return summary, m.LinkParents(ctx, parents)
+	}
+	return summary, nil
+}
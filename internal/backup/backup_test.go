@@ -0,0 +1,178 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"os"
+	"strings"
+	"testing"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+
+	"recipe.athif.com/internal/data"
+	"recipe.athif.com/migrations"
+)
+
+// testDSNVar names the environment variable holding the database the round trip runs
+// against. The test empties it, so it must be a migrated database kept for testing.
+const testDSNVar = "RECIPE_TEST_DB_DSN"
+
+// TestRoundTrip exports a catalog, restores it into the emptied database and exports
+// it again, which must give the same checksum. Restoring the backup a second time must
+// create nothing and change nothing.
+func TestRoundTrip(t *testing.T) {
+	dsn := os.Getenv(testDSNVar)
+	if dsn == "" {
+		t.Skipf("set %s to a migrated, disposable database to run this test", testDSNVar)
+	}
+
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	schemaVersion, err := migrations.Latest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	models := data.NewModels(db)
+	m := models.Backups
+
+	emptyDatabase(t, db)
+	seed(t, models)
+
+	var original bytes.Buffer
+	exported, err := Export(ctx, m, &original, schemaVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exported.Cuisines != 2 || exported.Ingredients < 3 || exported.Recipes != 2 {
+		t.Fatalf("exported %+v, want 2 cuisines, at least 3 ingredients and 2 recipes", exported)
+	}
+
+	emptyDatabase(t, db)
+
+	summary, err := Import(ctx, m, bytes.NewReader(original.Bytes()), schemaVersion, ImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Created != exported.Recipes || summary.Updated != 0 {
+		t.Errorf("first import created %d and updated %d recipes, want %d and 0", summary.Created, summary.Updated, exported.Recipes)
+	}
+
+	var restored bytes.Buffer
+	reexported, err := Export(ctx, m, &restored, schemaVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reexported != exported {
+		t.Errorf("exporting the restored database gave %+v, want %+v", reexported, exported)
+	}
+	if got, want := body(restored.String()), body(original.String()); got != want {
+		t.Errorf("exporting the restored database gave\n%s\nwant\n%s", got, want)
+	}
+
+	summary, err = Import(ctx, m, bytes.NewReader(original.Bytes()), schemaVersion, ImportOptions{BatchSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Created != 0 {
+		t.Errorf("second import created %d recipes, want 0", summary.Created)
+	}
+
+	var again bytes.Buffer
+	reexported, err = Export(ctx, m, &again, schemaVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reexported != exported {
+		t.Errorf("exporting after a second import gave %+v, want %+v", reexported, exported)
+	}
+}
+
+// emptyDatabase removes every cuisine, ingredient and recipe, along with everything
+// which refers to them.
+func emptyDatabase(t *testing.T, db *sql.DB) {
+	t.Helper()
+
+	_, err := db.Exec(`TRUNCATE cuisine, ingredients, recipes, tags RESTART IDENTITY CASCADE`)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// seed adds a small catalog: two cuisines, an ingredient with an alias, a published
+// recipe with steps, tags and an image, and a draft forked from it.
+func seed(t *testing.T, models data.Models) {
+	t.Helper()
+
+	for _, cuisine := range []*data.Cuisine{
+		{Name: "Italian", Slug: "italian"},
+		{Name: "Indian", Slug: "indian"},
+	} {
+		if err := models.Cuisines.Insert(cuisine); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := models.Ingredients.Insert(&data.CatalogIngredient{Name: "Tomato", Category: "vegetable", Aliases: []string{"tomatoes"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recipe := &data.Recipe{
+		Title:        "Tomato Pasta",
+		Instructions: "Cook the pasta. Make the sauce.",
+		Steps: []data.Step{
+			{Position: 1, Text: "Cook the pasta.", Duration: 10},
+			{Position: 2, Text: "Make the sauce.", Duration: 15, Ingredients: []string{"Tomato"}},
+		},
+		PrepTime:    10,
+		CookTime:    25,
+		Difficulty:  data.DifficultyEasy,
+		CuisineName: "Italian",
+		Ingredients: []data.Ingredient{
+			{IngredientName: "Pasta", Quantity: 200, Unit: "g"},
+			{IngredientName: "Tomato", Quantity: 4},
+			{IngredientName: "Olive oil", Quantity: 2, Unit: "tbsp"},
+		},
+		Tags:      []string{"vegetarian", "quick"},
+		ImageLink: "https://example.com/pasta.jpg",
+		Status:    data.StatusPublished,
+	}
+	if err := models.Recipes.Insert(recipe, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	fork := &data.Recipe{
+		Title:        "Spicy Tomato Pasta",
+		Instructions: "Cook the pasta. Make the sauce with chilli.",
+		PrepTime:     10,
+		CookTime:     25,
+		Difficulty:   data.DifficultyMedium,
+		CuisineName:  "Italian",
+		Ingredients: []data.Ingredient{
+			{IngredientName: "Pasta", Quantity: 200, Unit: "g"},
+			{IngredientName: "Tomato", Quantity: 4},
+			{IngredientName: "Chilli", Quantity: 1},
+		},
+		ParentID: recipe.ID,
+	}
+	if err := models.Recipes.Insert(fork, "bob"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// body returns the lines of a backup between the header and the trailer, which are
+// the ones the checksum covers.
+func body(backup string) string {
+	lines := strings.Split(strings.TrimSpace(backup), "\n")
+	if len(lines) < 2 {
+		return ""
+	}
+	return strings.Join(lines[1:len(lines)-1], "\n")
+}
@@ -0,0 +1,164 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// AuditEvent records one mutating API request. ResourceType and ResourceID name what
+// the request acted on, when the handler got far enough to know, and Changes lists
+// the fields it changed.
+type AuditEvent struct {
+	ID           int64     `json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	Actor        string    `json:"actor"`
+	RequestID    string    `json:"request_id"`
+	IP           string    `json:"ip"`
+	Method       string    `json:"method"`
+	Route        string    `json:"route"`
+	Status       int       `json:"status"`
+	ResourceType string    `json:"resource_type,omitempty"`
+	ResourceID   int64     `json:"resource_id,omitempty"`
+	Changes      []Change  `json:"changes"`
+}
+
+// AuditCriteria narrows a listing of audit events. Zero values are ignored.
+type AuditCriteria struct {
+	Actor        string
+	Method       string
+	Route        string
+	ResourceType string
+	ResourceID   int64
+	Since        time.Time
+	Until        time.Time
+}
+
+// DiffJSON lists the changes between two JSON documents. Nested objects are compared
+// field by field, with their names joined by dots, while lists and other values are
+// compared whole. A nil document is treated as an empty object, so the diff for a
+// created or deleted resource lists each of its fields.
+func DiffJSON(before, after []byte) ([]Change, error) {
+	var a, b map[string]any
+	if before != nil {
+		if err := json.Unmarshal(before, &a); err != nil {
+			return nil, err
+		}
+	}
+	if after != nil {
+		if err := json.Unmarshal(after, &b); err != nil {
+			return nil, err
+		}
+	}
+
+	changes := []Change{}
+	diffObjects("", a, b, &changes)
+	return changes, nil
+}
+
+func diffObjects(prefix string, a, b map[string]any, changes *[]Change) {
+	keys := make([]string, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		old, new := a[key], b[key]
+		oldObject, oldIsObject := old.(map[string]any)
+		newObject, newIsObject := new.(map[string]any)
+		switch {
+		case oldIsObject && newIsObject:
+			diffObjects(prefix+key+".", oldObject, newObject, changes)
+		case !reflect.DeepEqual(old, new):
+			*changes = append(*changes, Change{Field: prefix + key, Old: old, New: new})
+		}
+	}
+}
+
+type AuditModel struct {
+	DB *sql.DB
+}
+
+func (m AuditModel) Insert(event *AuditEvent) error {
+	changes, err := json.Marshal(event.Changes)
+	if err != nil {
+		return err
+	}
+
+	query := `
+        INSERT INTO audit_events (actor, request_id, ip, method, route, status, resource_type, resource_id, changes)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, 0), $9)
+        RETURNING id, created_at`
+
+	args := []any{event.Actor, event.RequestID, event.IP, event.Method, event.Route, event.Status, event.ResourceType, event.ResourceID, changes}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&event.ID, &event.CreatedAt)
+}
+
+func (m AuditModel) GetAll(criteria AuditCriteria, filters Filters) ([]*AuditEvent, Metadata, error) {
+	query := fmt.Sprintf(`
+        SELECT COUNT(*) OVER(), id, created_at, actor, request_id, ip, method, route, status,
+            resource_type, COALESCE(resource_id, 0), changes
+        FROM audit_events
+        WHERE (actor = $1 OR $1 = '')
+        AND (method = $2 OR $2 = '')
+        AND (route = $3 OR $3 = '')
+        AND (resource_type = $4 OR $4 = '')
+        AND (resource_id = $5 OR $5 = 0)
+        AND (created_at >= $6::timestamptz OR $6::timestamptz IS NULL)
+        AND (created_at < $7::timestamptz OR $7::timestamptz IS NULL)
+        ORDER BY %s %s, id %[2]s
+        LIMIT NULLIF($8, 0) OFFSET $9`, filters.sortColumn(), filters.sortDirection())
+
+	args := []any{criteria.Actor, criteria.Method, criteria.Route, criteria.ResourceType, criteria.ResourceID,
+		nullTime(criteria.Since), nullTime(criteria.Until), filters.limit(), filters.offset()}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	events := []*AuditEvent{}
+	for rows.Next() {
+		var event AuditEvent
+		var changes []byte
+		err := rows.Scan(&totalRecords, &event.ID, &event.CreatedAt, &event.Actor, &event.RequestID, &event.IP,
+			&event.Method, &event.Route, &event.Status, &event.ResourceType, &event.ResourceID, &changes)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		if err := json.Unmarshal(changes, &event.Changes); err != nil {
+			return nil, Metadata{}, err
+		}
+		events = append(events, &event)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return events, metadata, nil
+}
+
+// nullTime turns the zero time into NULL, so that an unset bound is ignored.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
@@ -0,0 +1,108 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"time"
+
+	"recipe.athif.com/internal/validator"
+)
+
+var ErrDuplicateAuthor = errors.New("duplicate author")
+
+// Author is someone who writes, saves and rates recipes. Recipes, favorites and ratings
+// are keyed by the author's name.
+type Author struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func ValidateAuthor(v *validator.Validator, author *Author) {
+	v.Check(author.Name != "", "name", "must be provided")
+	v.Check(len(author.Name) >= 3, "name", "must be at least 3 bytes long")
+	v.Check(len(author.Name) <= 30, "name", "must not be more than 30 bytes long")
+	// Names take the same form as cuisine slugs, which can't match the IP addresses
+	// recipes were keyed by before authors existed.
+	v.Check(validator.Matches(author.Name, slugRX), "name", "must contain only lower case letters, digits and single hyphens")
+	v.Check(author.Name != "admin" && author.Name != "anonymous", "name", "is reserved")
+}
+
+// ValidateTokenPlaintext checks that token has the shape of a token from
+// newAuthorToken.
+func ValidateTokenPlaintext(v *validator.Validator, token string) {
+	v.Check(len(token) == 26, "token", "must be 26 bytes long")
+}
+
+type AuthorModel struct {
+	DB *sql.DB
+}
+
+// newAuthorToken returns a random token and the hash of it to store.
+func newAuthorToken() (string, []byte, error) {
+	random := make([]byte, 16)
+	_, err := rand.Read(random)
+	if err != nil {
+		return "", nil, err
+	}
+	token := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(random)
+	hash := sha256.Sum256([]byte(token))
+	return token, hash[:], nil
+}
+
+// Insert registers the author, returning the token they authenticate with. Only its
+// hash is stored, so it can't be shown again.
+func (m AuthorModel) Insert(author *Author) (string, error) {
+	token, hash, err := newAuthorToken()
+	if err != nil {
+		return "", err
+	}
+
+	query := `
+        INSERT INTO authors (name, token_hash)
+        VALUES ($1, $2)
+        RETURNING id, created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err = m.DB.QueryRowContext(ctx, query, author.Name, hash).Scan(&author.ID, &author.CreatedAt)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err):
+			return "", ErrDuplicateAuthor
+		default:
+			return "", err
+		}
+	}
+	return token, nil
+}
+
+// GetForToken returns the author the token was issued to.
+func (m AuthorModel) GetForToken(token string) (*Author, error) {
+	hash := sha256.Sum256([]byte(token))
+
+	query := `
+        SELECT id, name, created_at
+        FROM authors
+        WHERE token_hash = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var author Author
+	err := m.DB.QueryRowContext(ctx, query, hash[:]).Scan(&author.ID, &author.Name, &author.CreatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &author, nil
+}
@@ -0,0 +1,326 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"time"
+
+	"recipe.athif.com/internal/validator"
+)
+
+var uuidRX = regexp.MustCompile("^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$")
+
+// BackupCuisine, BackupIngredient and BackupRecipe are the records of a backup. They
+// refer to each other by name or uid rather than by ID, since IDs are local to a
+// database and are remapped when a backup is restored.
+type BackupCuisine struct {
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+type BackupIngredient struct {
+	Name     string   `json:"name"`
+	Category string   `json:"category"`
+	Aliases  []string `json:"aliases"`
+}
+
+// BackupRecipe's workflow fields are left out of backups written before recipes had a
+// status, when every recipe was published. A fork names its parent by uid.
+type BackupRecipe struct {
+	UID       string `json:"uid"`
+	ParentUID string `json:"parent_uid,omitempty"`
+	RecipeSnapshot
+	Status       Status     `json:"status,omitempty"`
+	Author       string     `json:"author,omitempty"`
+	ReviewReason string     `json:"review_reason,omitempty"`
+	PublishAt    *time.Time `json:"publish_at,omitempty"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+type BackupRecipeIngredient struct {
+	Name     string  `json:"name"`
+	Quantity float32 `json:"quantity"`
+	Unit     string  `json:"unit"`
+}
+
+// recipe converts the backup record to the Recipe it restores.
+func (b *BackupRecipe) recipe() *Recipe {
+	recipe := b.RecipeSnapshot.recipe()
+	recipe.Status = b.Status
+	if recipe.Status == "" {
+		recipe.Status = StatusPublished
+	}
+	recipe.Author = b.Author
+	recipe.ReviewReason = b.ReviewReason
+	recipe.PublishAt = b.PublishAt
+	recipe.UpdatedAt = b.UpdatedAt
+	return recipe
+}
+
+func ValidateBackupRecipe(v *validator.Validator, b *BackupRecipe) {
+	v.Check(validator.Matches(b.UID, uuidRX), "uid", "must be a lower case UUID")
+	if b.ParentUID != "" {
+		v.Check(validator.Matches(b.ParentUID, uuidRX), "parent_uid", "must be a lower case UUID")
+		v.Check(b.ParentUID != b.UID, "parent_uid", "must not be the recipe's own uid")
+	}
+	v.Check(!b.UpdatedAt.IsZero(), "updated_at", "must be provided")
+	if b.Status != "" {
+		v.Check(validator.PermittedValue(b.Status, Statuses...), "status", "must be one of draft, pending_review, published or rejected")
+	}
+	ValidateRecipe(v, b.recipe())
+}
+
+type BackupModel struct {
+	DB *sql.DB
+}
+
+// Cuisines returns every cuisine, ordered by slug.
+func (m BackupModel) Cuisines(ctx context.Context) ([]BackupCuisine, error) {
+	rows, err := m.DB.QueryContext(ctx, `SELECT cuisinename, slug FROM cuisine ORDER BY slug`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cuisines := []BackupCuisine{}
+	for rows.Next() {
+		var cuisine BackupCuisine
+		err := rows.Scan(&cuisine.Name, &cuisine.Slug)
+		if err != nil {
+			return nil, err
+		}
+		cuisines = append(cuisines, cuisine)
+	}
+	return cuisines, rows.Err()
+}
+
+// Ingredients returns the whole ingredient catalog, ordered by name.
+func (m BackupModel) Ingredients(ctx context.Context) ([]BackupIngredient, error) {
+	query := `
+        SELECT i.ingredientname, i.category,
+            COALESCE((SELECT json_agg(a.alias ORDER BY a.alias) FROM ingredient_aliases a WHERE a.ingredientid = i.ingredientid), '[]')
+        FROM ingredients i
+        ORDER BY i.ingredientname`
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ingredients := []BackupIngredient{}
+	for rows.Next() {
+		var ingredient BackupIngredient
+		var aliases []byte
+		err := rows.Scan(&ingredient.Name, &ingredient.Category, &aliases)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(aliases, &ingredient.Aliases); err != nil {
+			return nil, err
+		}
+		ingredients = append(ingredients, ingredient)
+	}
+	return ingredients, rows.Err()
+}
+
+// Recipes returns up to limit recipes whose uid sorts after the given one, in uid
+// order, so that the whole table can be paged through without holding it in memory.
+// Pass an empty uid to start from the beginning. Recipes in the trash are left out.
+func (m BackupModel) Recipes(ctx context.Context, afterUID string, limit int) ([]*BackupRecipe, error) {
+	if afterUID == "" {
+		afterUID = "00000000-0000-0000-0000-000000000000"
+	}
+
+	query := `
+    SELECT r.uid::text, COALESCE(p.uid::text, ''),` + recipeColumns + `
+    FROM recipes r
+    INNER JOIN cuisine c ON r.cuisineid = c.cuisineid
+    LEFT JOIN recipe_images img ON r.recipeid = img.recipeid
+    LEFT JOIN recipes p ON p.recipeid = r.parent_id AND p.deleted_at IS NULL
+    WHERE r.uid > $1::uuid AND r.deleted_at IS NULL
+    ORDER BY r.uid
+    LIMIT $2`
+
+	rows, err := m.DB.QueryContext(ctx, query, afterUID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recipes []*Recipe
+	var uids, parentUIDs []string
+	for rows.Next() {
+		var uid, parentUID string
+		recipe, err := scanRecipe(rows, &uid, &parentUID)
+		if err != nil {
+			return nil, err
+		}
+		recipes = append(recipes, recipe)
+		uids = append(uids, uid)
+		parentUIDs = append(parentUIDs, parentUID)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = loadRecipeDetails(ctx, m.DB, recipes)
+	if err != nil {
+		return nil, err
+	}
+
+	backups := make([]*BackupRecipe, len(recipes))
+	for i, recipe := range recipes {
+		backup := &BackupRecipe{
+			UID:            uids[i],
+			ParentUID:      parentUIDs[i],
+			RecipeSnapshot: snapshotOf(recipe),
+			Status:         recipe.Status,
+			Author:         recipe.Author,
+			ReviewReason:   recipe.ReviewReason,
+			PublishAt:      recipe.PublishAt,
+			UpdatedAt:      recipe.UpdatedAt.UTC(),
+		}
+		if backup.PublishAt != nil {
+			publishAt := backup.PublishAt.UTC()
+			backup.PublishAt = &publishAt
+		}
+		backups[i] = backup
+	}
+	return backups, nil
+}
+
+// LinkParents points each recipe named by a key of links at the recipe named by its
+// value, both by uid, once the whole backup has been restored and every parent exists.
+// Links to recipes which aren't in the database are left unset.
+func (m BackupModel) LinkParents(ctx context.Context, links map[string]string) error {
+	children := make([]string, 0, len(links))
+	parents := make([]string, 0, len(links))
+	for child, parent := range links {
+		children = append(children, child)
+		parents = append(parents, parent)
+	}
+
+	query := `
+        UPDATE recipes r
+        SET parent_id = p.recipeid
+        FROM unnest($1::uuid[], $2::uuid[]) AS l (child, parent)
+        INNER JOIN recipes p ON p.uid = l.parent
+        WHERE r.uid = l.child AND r.parent_id IS DISTINCT FROM p.recipeid`
+
+	_, err := m.DB.ExecContext(ctx, query, children, parents)
+	return err
+}
+
+// Restorer writes backup records inside a single transaction. Every write is an
+// upsert keyed on a name or uid, so restoring the same backup twice leaves the
+// database as it was after the first time.
+type Restorer struct {
+	ctx context.Context
+	tx  *sql.Tx
+}
+
+func (m BackupModel) BeginRestore(ctx context.Context) (*Restorer, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Restorer{ctx: ctx, tx: tx}, nil
+}
+
+func (r *Restorer) Commit() error {
+	return r.tx.Commit()
+}
+
+func (r *Restorer) Rollback() error {
+	return r.tx.Rollback()
+}
+
+// Cuisine adds the cuisine unless one with the same name or slug already exists.
+func (r *Restorer) Cuisine(cuisine BackupCuisine) error {
+	_, err := r.tx.ExecContext(r.ctx, `INSERT INTO cuisine (cuisinename, slug) VALUES ($1, $2) ON CONFLICT DO NOTHING`, cuisine.Name, cuisine.Slug)
+	return err
+}
+
+// Ingredient adds the catalog entry or updates the category of an existing one, and
+// moves the aliases to it from any other entry holding them.
+func (r *Restorer) Ingredient(ingredient BackupIngredient) error {
+	query := `
+        INSERT INTO ingredients (ingredientname, category)
+        VALUES ($1, $2)
+        ON CONFLICT (ingredientname) DO UPDATE SET category = EXCLUDED.category
+        RETURNING ingredientid`
+
+	var id int64
+	err := r.tx.QueryRowContext(r.ctx, query, ingredient.Name, ingredient.Category).Scan(&id)
+	if err != nil {
+		return err
+	}
+
+	for _, alias := range ingredient.Aliases {
+		_, err = r.tx.ExecContext(r.ctx, `
+            INSERT INTO ingredient_aliases (alias, ingredientid) VALUES ($1, $2)
+            ON CONFLICT (alias) DO UPDATE SET ingredientid = EXCLUDED.ingredientid`,
+			strings.ToLower(alias), id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Recipe inserts the recipe, or replaces the one with the same uid, keeping its
+// updated_at from the backup and taking it out of the trash if it is there. The result
+// is recorded as a new revision by "restore". It reports whether the recipe was new.
+func (r *Restorer) Recipe(backup *BackupRecipe) (bool, error) {
+	recipe := backup.recipe()
+
+	cuisine, err := lookupCuisine(r.ctx, r.tx, recipe.CuisineName)
+	if err != nil {
+		return false, err
+	}
+
+	query := `
+        INSERT INTO recipes (uid, recipename, instructions, preparationtime, cookingtime, difficultylevel, cuisineid,
+            status, author, review_reason, publish_at, updated_at)
+        VALUES ($1::uuid, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+        ON CONFLICT (uid) DO UPDATE SET
+            recipename = EXCLUDED.recipename, instructions = EXCLUDED.instructions,
+            preparationtime = EXCLUDED.preparationtime, cookingtime = EXCLUDED.cookingtime,
+            difficultylevel = EXCLUDED.difficultylevel, cuisineid = EXCLUDED.cuisineid,
+            status = EXCLUDED.status, author = EXCLUDED.author,
+            review_reason = EXCLUDED.review_reason, publish_at = EXCLUDED.publish_at,
+            updated_at = EXCLUDED.updated_at, deleted_at = NULL
+        RETURNING recipeid, xmax = 0`
+
+	args := []any{backup.UID, recipe.Title, recipe.Instructions, recipe.PrepTime, recipe.CookTime, recipe.Difficulty, cuisine.ID,
+		recipe.Status, recipe.Author, recipe.ReviewReason, recipe.PublishAt, recipe.UpdatedAt}
+
+	var created bool
+	err = r.tx.QueryRowContext(r.ctx, query, args...).Scan(&recipe.ID, &created)
+	if err != nil {
+		return false, err
+	}
+
+	err = replaceImage(r.ctx, r.tx, recipe.ID, recipe.ImageLink)
+	if err != nil {
+		return false, err
+	}
+
+	err = replaceSteps(r.ctx, r.tx, recipe.ID, recipe.Steps)
+	if err != nil {
+		return false, err
+	}
+	err = replaceTags(r.ctx, r.tx, recipe.ID, recipe.Tags)
+	if err != nil {
+		return false, err
+	}
+	err = replaceIngredients(r.ctx, r.tx, recipe.ID, recipe.Ingredients)
+	if err != nil {
+		return false, err
+	}
+	return created, writeRevision(r.ctx, r.tx, recipe, "restore")
+}
@@ -0,0 +1,228 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"recipe.athif.com/internal/validator"
+)
+
+var (
+	ErrUnknownCuisine   = errors.New("unknown cuisine")
+	ErrDuplicateCuisine = errors.New("duplicate cuisine")
+	ErrCuisineInUse     = errors.New("cuisine is used by recipes")
+)
+
+var (
+	slugRX    = regexp.MustCompile("^[a-z0-9]+(?:-[a-z0-9]+)*$")
+	nonSlugRX = regexp.MustCompile("[^a-z0-9]+")
+)
+
+type Cuisine struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Slug        string `json:"slug"`
+	RecipeCount int    `json:"recipe_count"`
+}
+
+// Slugify lower-cases s and replaces every run of characters other than letters and
+// digits with a single hyphen, so "Middle Eastern" becomes "middle-eastern".
+func Slugify(s string) string {
+	return strings.Trim(nonSlugRX.ReplaceAllString(strings.ToLower(s), "-"), "-")
+}
+
+func ValidateCuisine(v *validator.Validator, cuisine *Cuisine) {
+	v.Check(cuisine.Name != "", "name", "must be provided")
+	v.Check(len(cuisine.Name) <= 100, "name", "must not be more than 100 bytes long")
+	v.Check(cuisine.Slug != "", "slug", "must be provided")
+	v.Check(validator.Matches(cuisine.Slug, slugRX), "slug", "must contain only lower case letters, digits and single hyphens")
+}
+
+type CuisineModel struct {
+	DB *sql.DB
+}
+
+func (m CuisineModel) Insert(cuisine *Cuisine) error {
+	query := `
+        INSERT INTO cuisine (cuisinename, slug)
+        VALUES ($1, $2)
+        RETURNING cuisineid`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, cuisine.Name, cuisine.Slug).Scan(&cuisine.ID)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err):
+			return ErrDuplicateCuisine
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (m CuisineModel) Get(id int64) (*Cuisine, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+        SELECT c.cuisineid, c.cuisinename, c.slug, COUNT(r.recipeid)
+        FROM cuisine c
+        LEFT JOIN recipes r ON r.cuisineid = c.cuisineid AND r.deleted_at IS NULL AND r.status = 'published'
+        WHERE c.cuisineid = $1
+        GROUP BY c.cuisineid`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var cuisine Cuisine
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(&cuisine.ID, &cuisine.Name, &cuisine.Slug, &cuisine.RecipeCount)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &cuisine, nil
+}
+
+func (m CuisineModel) GetAll(name string, filters Filters) ([]*Cuisine, Metadata, error) {
+	sortColumn := filters.sortColumn()
+	switch sortColumn {
+	case "id":
+		sortColumn = "c.cuisineid"
+	case "name":
+		sortColumn = "c.cuisinename"
+	case "recipe_count":
+		sortColumn = "COUNT(r.recipeid)"
+	}
+
+	query := fmt.Sprintf(`
+        SELECT c.cuisineid, c.cuisinename, c.slug, COUNT(r.recipeid)
+        FROM cuisine c
+        LEFT JOIN recipes r ON r.cuisineid = c.cuisineid AND r.deleted_at IS NULL AND r.status = 'published'
+        WHERE (LOWER(c.cuisinename) LIKE LOWER($1) OR $1 = '')
+        GROUP BY c.cuisineid
+        ORDER BY %s %s, c.cuisineid ASC`, sortColumn, filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, "%"+name+"%")
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	cuisines := []*Cuisine{}
+	for rows.Next() {
+		var cuisine Cuisine
+		err := rows.Scan(&cuisine.ID, &cuisine.Name, &cuisine.Slug, &cuisine.RecipeCount)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		cuisines = append(cuisines, &cuisine)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(len(cuisines), filters.Page, filters.PageSize)
+	return cuisines, metadata, nil
+}
+
+// Update renames a cuisine. Its recipes show the cuisine's name, so their updated_at is
+// advanced in the same statement.
+func (m CuisineModel) Update(cuisine *Cuisine) error {
+	query := `
+        WITH updated AS (
+            UPDATE cuisine
+            SET cuisinename = $1, slug = $2
+            WHERE cuisineid = $3
+            RETURNING cuisineid
+        ), touched AS (
+            UPDATE recipes SET updated_at = NOW()
+            WHERE cuisineid IN (SELECT cuisineid FROM updated)
+        )
+        SELECT cuisineid FROM updated`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, cuisine.Name, cuisine.Slug, cuisine.ID).Scan(&cuisine.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		case isUniqueViolation(err):
+			return ErrDuplicateCuisine
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (m CuisineModel) Delete(id int64) error {
+	query := `DELETE FROM cuisine WHERE cuisineid = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		switch {
+		case isForeignKeyViolation(err):
+			return ErrCuisineInUse
+		default:
+			return err
+		}
+	}
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// Lookup finds a cuisine by its slug or (case-insensitively) its name, returning
+// ErrUnknownCuisine if there is no such cuisine. RecipeCount is left at zero.
+func (m CuisineModel) Lookup(nameOrSlug string) (*Cuisine, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return lookupCuisine(ctx, m.DB, nameOrSlug)
+}
+
+// lookupCuisine finds a cuisine by its slug or (case-insensitively) its name, returning
+// ErrUnknownCuisine if there is no such cuisine. RecipeCount is left at zero.
+func lookupCuisine(ctx context.Context, db queryer, nameOrSlug string) (*Cuisine, error) {
+	query := `
+        SELECT cuisineid, cuisinename, slug
+        FROM cuisine
+        WHERE slug = LOWER($1) OR LOWER(cuisinename) = LOWER($1)
+        ORDER BY cuisineid
+        LIMIT 1`
+
+	var cuisine Cuisine
+	err := db.QueryRowContext(ctx, query, strings.TrimSpace(nameOrSlug)).Scan(&cuisine.ID, &cuisine.Name, &cuisine.Slug)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrUnknownCuisine
+		default:
+			return nil, err
+		}
+	}
+	return &cuisine, nil
+}
@@ -0,0 +1,90 @@
+package data
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrInvalidDifficulty = errors.New("invalid difficulty")
+
+// Difficulty is how hard a recipe is to make. The levels are ordered, from
+// DifficultyEasy to DifficultyAdvanced, and the database only accepts these values.
+type Difficulty string
+
+const (
+	DifficultyEasy     Difficulty = "Easy"
+	DifficultyMedium   Difficulty = "Medium"
+	DifficultyAdvanced Difficulty = "Advanced"
+)
+
+// Difficulties lists every difficulty level from easiest to hardest.
+var Difficulties = []Difficulty{DifficultyEasy, DifficultyMedium, DifficultyAdvanced}
+
+// difficultyRank is the SQL equivalent of Difficulty.Rank for the recipes table.
+const difficultyRank = `CASE r.difficultylevel
+                        WHEN 'Easy' THEN 1
+                        WHEN 'Medium' THEN 2
+                        WHEN 'Advanced' THEN 3
+                      END`
+
+// ParseDifficulty returns the difficulty named by s, ignoring case.
+func ParseDifficulty(s string) (Difficulty, error) {
+	for _, d := range Difficulties {
+		if strings.EqualFold(s, string(d)) {
+			return d, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q", ErrInvalidDifficulty, s)
+}
+
+// Rank returns the position of d in Difficulties counting from one, or zero if d isn't
+// a known difficulty.
+func (d Difficulty) Rank() int {
+	for i, known := range Difficulties {
+		if d == known {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// UnmarshalJSON accepts any capitalisation of a difficulty. Unknown values are kept as
+// they are, so that they're reported by ValidateRecipe along with any other problems
+// rather than rejecting the whole request body.
+func (d *Difficulty) UnmarshalJSON(jsonValue []byte) error {
+	var s string
+	err := json.Unmarshal(jsonValue, &s)
+	if err != nil {
+		return ErrInvalidDifficulty
+	}
+	parsed, err := ParseDifficulty(s)
+	if err != nil {
+		*d = Difficulty(s)
+		return nil
+	}
+	*d = parsed
+	return nil
+}
+
+func (d Difficulty) Value() (driver.Value, error) {
+	return string(d), nil
+}
+
+// DifficultyRange selects the difficulties from Min to Max inclusive. A zero Min or Max
+// leaves that end of the range open.
+type DifficultyRange struct {
+	Min Difficulty
+	Max Difficulty
+}
+
+// bounds returns the range as ranks, for comparing with difficultyRank.
+func (dr DifficultyRange) bounds() (int, int) {
+	max := dr.Max.Rank()
+	if max == 0 {
+		max = len(Difficulties)
+	}
+	return dr.Min.Rank(), max
+}
@@ -0,0 +1,59 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Favorite is a recipe saved by an actor.
+type Favorite struct {
+	RecipeID  int       `json:"recipe_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type FavoriteModel struct {
+	DB *sql.DB
+}
+
+// Add saves the recipe as one of the actor's favorites. Saving a recipe twice keeps
+// the time it was first saved.
+func (m FavoriteModel) Add(actor string, recipeID int64) (*Favorite, error) {
+	query := `
+        INSERT INTO recipe_favorites (actor, recipeid)
+        VALUES ($1, $2)
+        ON CONFLICT (actor, recipeid) DO UPDATE SET created_at = recipe_favorites.created_at
+        RETURNING recipeid, created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var favorite Favorite
+	err := m.DB.QueryRowContext(ctx, query, actor, recipeID).Scan(&favorite.RecipeID, &favorite.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &favorite, nil
+}
+
+// Remove drops the recipe from the actor's favorites, returning ErrRecordNotFound if it
+// wasn't one of them.
+func (m FavoriteModel) Remove(actor string, recipeID int64) error {
+	query := `DELETE FROM recipe_favorites WHERE actor = $1 AND recipeid = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, actor, recipeID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
@@ -1,7 +1,7 @@
 package data
 
 import (
-	//"math"
+	"math"
 	"strings"
 
 	"recipe.athif.com/internal/validator"
@@ -18,8 +18,11 @@ func ValidateFilters(v *validator.Validator, f Filters) {
 	// Check that the page and page_size parameters contain sensible values.
 	v.Check(f.Page > 0, "page", "must be greater than zero")
 	v.Check(f.Page <= 10_000_000, "page", "must be a maximum of 10 million")
-	//v.Check(f.PageSize > 0, "pagesize", "must be greater than zero")
-	//v.Check(f.PageSize <= 100, "pagesize", "must be a maximum of 100")
+	// Listings which aren't paginated leave PageSize at zero.
+	if f.PageSize != 0 {
+		v.Check(f.PageSize > 0, "page_size", "must be greater than zero")
+		v.Check(f.PageSize <= 100, "page_size", "must be a maximum of 100")
+	}
 	// Check that the sort parameter matches a value in the safelist.
 	v.Check(validator.PermittedValue(f.Sort, f.SortSafelist...), "sort", "invalid sort value")
 }
@@ -49,21 +52,26 @@ func (f Filters) offset() int {
 
 type Metadata struct {
 	CurrentPage  int `json:"current_page,omitempty"`
-	//PageSize     int `json:"page_size,omitempty"`
+	PageSize     int `json:"page_size,omitempty"`
 	FirstPage    int `json:"first_page,omitempty"`
 	LastPage     int `json:"last_page,omitempty"`
 	TotalRecords int `json:"total_records,omitempty"`
 }
 
-func calculateMetadata(totalRecords, page int) Metadata {
+// calculateMetadata works out the pagination metadata. A pageSize of zero means the
+// listing isn't paginated, so there is no last page.
+func calculateMetadata(totalRecords, page, pageSize int) Metadata {
 	if totalRecords == 0 {
 		return Metadata{}
 	}
-	return Metadata{
+	metadata := Metadata{
 		CurrentPage:  page,
-		//PageSize:     pageSize,
+		PageSize:     pageSize,
 		FirstPage:    1,
-		//LastPage:     int(math.Ceil(float64(totalRecords) / float64(pageSize))),
 		TotalRecords: totalRecords,
 	}
+	if pageSize > 0 {
+		metadata.LastPage = int(math.Ceil(float64(totalRecords) / float64(pageSize)))
+	}
+	return metadata
 }
@@ -0,0 +1,69 @@
+package data
+
+import (
+	"context"
+	"time"
+)
+
+// maxLineageDepth bounds how far GetLineage follows parent links.
+const maxLineageDepth = 100
+
+// LineageEntry is one recipe in a line of forks. Deleted is set for recipes in the
+// trash, which are kept in the line so that it isn't broken.
+type LineageEntry struct {
+	ID         int    `json:"id"`
+	Title      string `json:"title,omitempty"`
+	Status     Status `json:"status,omitempty"`
+	Deleted    bool   `json:"deleted,omitempty"`
+	Variations int    `json:"variations"`
+	// Author decides whether the entry may be shown, as for Recipe.
+	Author string `json:"-"`
+}
+
+// GetLineage returns the recipe's ancestors, starting from the original recipe, and
+// then the recipe itself. Variations counts each recipe's direct forks which aren't in
+// the trash.
+func (r RecipeModel) GetLineage(id int64) ([]*LineageEntry, error) {
+	query := `
+        WITH RECURSIVE lineage AS (
+            SELECT recipeid, parent_id, 0 AS depth
+            FROM recipes
+            WHERE recipeid = $1 AND deleted_at IS NULL
+            UNION ALL
+            SELECT p.recipeid, p.parent_id, l.depth + 1
+            FROM recipes p
+            INNER JOIN lineage l ON p.recipeid = l.parent_id
+            WHERE l.depth < $2
+        )
+        SELECT r.recipeid, r.recipename, r.status, r.deleted_at IS NOT NULL, r.author,
+            (SELECT COUNT(*) FROM recipes f WHERE f.parent_id = r.recipeid AND f.deleted_at IS NULL)
+        FROM lineage l
+        INNER JOIN recipes r ON r.recipeid = l.recipeid
+        ORDER BY l.depth DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := r.DB.QueryContext(ctx, query, id, maxLineageDepth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lineage := []*LineageEntry{}
+	for rows.Next() {
+		var entry LineageEntry
+		err := rows.Scan(&entry.ID, &entry.Title, &entry.Status, &entry.Deleted, &entry.Author, &entry.Variations)
+		if err != nil {
+			return nil, err
+		}
+		lineage = append(lineage, &entry)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(lineage) == 0 {
+		return nil, ErrRecordNotFound
+	}
+	return lineage, nil
+}
@@ -0,0 +1,45 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+type HealthModel struct {
+	DB *sql.DB
+}
+
+// Ping checks that a connection to the database can be established.
+func (m HealthModel) Ping(ctx context.Context) error {
+	return m.DB.PingContext(ctx)
+}
+
+// Stats returns the connection pool statistics.
+func (m HealthModel) Stats() sql.DBStats {
+	return m.DB.Stats()
+}
+
+// SchemaVersion returns the migration version recorded by golang-migrate, and whether
+// the last migration failed part way through. A database which has never been migrated
+// reports version 0.
+func (m HealthModel) SchemaVersion(ctx context.Context) (uint, bool, error) {
+	var exists bool
+	err := m.DB.QueryRowContext(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists)
+	if err != nil || !exists {
+		return 0, false, err
+	}
+
+	var version int64
+	var dirty bool
+	err = m.DB.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, false, nil
+		default:
+			return 0, false, err
+		}
+	}
+	return uint(version), dirty, nil
+}
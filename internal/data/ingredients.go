@@ -0,0 +1,376 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"recipe.athif.com/internal/validator"
+)
+
+var (
+	ErrDuplicateIngredient = errors.New("duplicate ingredient")
+)
+
+// CatalogIngredient is an entry in the ingredient catalog, as opposed to Ingredient
+// which is one line of a recipe.
+type CatalogIngredient struct {
+	ID          int64    `json:"id"`
+	Name        string   `json:"name"`
+	Category    string   `json:"category"`
+	Aliases     []string `json:"aliases"`
+	RecipeCount int      `json:"recipe_count"`
+}
+
+func ValidateCatalogIngredient(v *validator.Validator, ingredient *CatalogIngredient) {
+	v.Check(ingredient.Name != "", "name", "must be provided")
+	v.Check(len(ingredient.Name) <= 100, "name", "must not be more than 100 bytes long")
+	v.Check(len(ingredient.Category) <= 50, "category", "must not be more than 50 bytes long")
+	v.Check(len(ingredient.Aliases) <= 20, "aliases", "must not contain more than 20 aliases")
+	for _, alias := range ingredient.Aliases {
+		v.Check(alias != "", "aliases", "must not contain empty values")
+		v.Check(len(alias) <= 100, "aliases", "must not contain values more than 100 bytes long")
+	}
+	v.Check(validator.Unique(ingredient.Aliases), "aliases", "must not contain duplicate values")
+}
+
+// normalizeAliases lower-cases and trims the aliases, dropping any that are just the
+// ingredient's own name.
+func normalizeAliases(name string, aliases []string) []string {
+	normalized := make([]string, 0, len(aliases))
+	for _, alias := range aliases {
+		alias = strings.ToLower(strings.TrimSpace(alias))
+		if alias != strings.ToLower(name) {
+			normalized = append(normalized, alias)
+		}
+	}
+	return normalized
+}
+
+type IngredientModel struct {
+	DB          *sql.DB
+	suggestions *suggestionCache
+}
+
+const catalogIngredientColumns = `
+        i.ingredientid, i.ingredientname, i.category,
+        COALESCE((SELECT json_agg(a.alias ORDER BY a.alias) FROM ingredient_aliases a WHERE a.ingredientid = i.ingredientid), '[]'),
+        (SELECT COUNT(DISTINCT ri.recipeid) FROM recipeingredients ri INNER JOIN recipes r USING (recipeid) WHERE ri.ingredientid = i.ingredientid AND r.deleted_at IS NULL AND r.status = 'published')`
+
+func scanCatalogIngredient(row interface{ Scan(...any) error }, dest ...any) (*CatalogIngredient, error) {
+	var ingredient CatalogIngredient
+	var aliases []byte
+	dest = append(dest, &ingredient.ID, &ingredient.Name, &ingredient.Category, &aliases, &ingredient.RecipeCount)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(aliases, &ingredient.Aliases); err != nil {
+		return nil, err
+	}
+	return &ingredient, nil
+}
+
+func (m IngredientModel) Insert(ingredient *CatalogIngredient) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+        INSERT INTO ingredients (ingredientname, category)
+        VALUES ($1, $2)
+        RETURNING ingredientid`
+
+	err = tx.QueryRowContext(ctx, query, ingredient.Name, ingredient.Category).Scan(&ingredient.ID)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err):
+			return ErrDuplicateIngredient
+		default:
+			return err
+		}
+	}
+
+	ingredient.Aliases = normalizeAliases(ingredient.Name, ingredient.Aliases)
+	err = replaceAliases(ctx, tx, ingredient.ID, ingredient.Aliases)
+	if err != nil {
+		return err
+	}
+	err = touchRecipesUsing(ctx, tx, []int64{ingredient.ID})
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	m.suggestions.invalidate()
+	return nil
+}
+
+func (m IngredientModel) Get(id int64) (*CatalogIngredient, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `SELECT` + catalogIngredientColumns + `
+        FROM ingredients i
+        WHERE i.ingredientid = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	ingredient, err := scanCatalogIngredient(m.DB.QueryRowContext(ctx, query, id))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return ingredient, nil
+}
+
+// GetAll lists catalog ingredients whose name or one of whose aliases starts with
+// prefix, optionally restricted to a category.
+func (m IngredientModel) GetAll(prefix string, category string, filters Filters) ([]*CatalogIngredient, Metadata, error) {
+	sortColumn := filters.sortColumn()
+	switch sortColumn {
+	case "id":
+		sortColumn = "i.ingredientid"
+	case "name":
+		sortColumn = "LOWER(i.ingredientname)"
+	case "recipe_count":
+		sortColumn = "(SELECT COUNT(DISTINCT ri.recipeid) FROM recipeingredients ri INNER JOIN recipes r USING (recipeid) WHERE ri.ingredientid = i.ingredientid AND r.deleted_at IS NULL AND r.status = 'published')"
+	}
+
+	query := fmt.Sprintf(`
+        SELECT COUNT(*) OVER(),`+catalogIngredientColumns+`
+        FROM ingredients i
+        WHERE ($1 = ''
+            OR LOWER(i.ingredientname) LIKE $1 || '%%'
+            OR EXISTS (SELECT 1 FROM ingredient_aliases a WHERE a.ingredientid = i.ingredientid AND a.alias LIKE $1 || '%%'))
+        AND (LOWER(i.category) = LOWER($2) OR $2 = '')
+        ORDER BY %s %s, i.ingredientid ASC
+        LIMIT NULLIF($3, 0) OFFSET $4`, sortColumn, filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, likePrefix(prefix), category, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	ingredients := []*CatalogIngredient{}
+	for rows.Next() {
+		ingredient, err := scanCatalogIngredient(rows, &totalRecords)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		ingredients = append(ingredients, ingredient)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return ingredients, metadata, nil
+}
+
+func (m IngredientModel) Update(ingredient *CatalogIngredient) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+        UPDATE ingredients
+        SET ingredientname = $1, category = $2
+        WHERE ingredientid = $3
+        RETURNING ingredientid`
+
+	err = tx.QueryRowContext(ctx, query, ingredient.Name, ingredient.Category, ingredient.ID).Scan(&ingredient.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		case isUniqueViolation(err):
+			return ErrDuplicateIngredient
+		default:
+			return err
+		}
+	}
+
+	ingredient.Aliases = normalizeAliases(ingredient.Name, ingredient.Aliases)
+	err = replaceAliases(ctx, tx, ingredient.ID, ingredient.Aliases)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	m.suggestions.invalidate()
+	return nil
+}
+
+// touchRecipesUsing advances updated_at on the recipes listing any of the ingredients,
+// whose names are part of those recipes, so that caches revalidate them.
+func touchRecipesUsing(ctx context.Context, db queryer, ingredientIDs []int64) error {
+	query := `
+        UPDATE recipes SET updated_at = NOW()
+        WHERE recipeid IN (
+            SELECT recipeid FROM recipeingredients WHERE ingredientid = ANY($1::integer[])
+        )`
+
+	_, err := db.ExecContext(ctx, query, ingredientIDs)
+	return err
+}
+
+// Merge folds the source ingredients into the target: recipe lines are repointed at the
+// target, the source names become aliases of it, and the sources are deleted, all in
+// one transaction. Where a recipe already lists the target (or several of the
+// sources), the duplicate lines are dropped and the target's quantity is kept.
+func (m IngredientModel) Merge(targetID int64, sourceIDs []int64) (*CatalogIngredient, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var found int
+	err = tx.QueryRowContext(ctx, `
+        SELECT COUNT(*) FROM (
+            SELECT ingredientid FROM ingredients
+            WHERE ingredientid = $1 OR ingredientid = ANY($2)
+            FOR UPDATE
+        ) locked`, targetID, sourceIDs).Scan(&found)
+	if err != nil {
+		return nil, err
+	}
+	if found != len(sourceIDs)+1 {
+		return nil, ErrRecordNotFound
+	}
+
+	err = touchRecipesUsing(ctx, tx, sourceIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	statements := []string{
+		`DELETE FROM recipeingredients ri
+         WHERE ri.ingredientid = ANY($2)
+         AND EXISTS (SELECT 1 FROM recipeingredients t WHERE t.recipeid = ri.recipeid AND t.ingredientid = $1)`,
+		`DELETE FROM recipeingredients ri
+         USING recipeingredients other
+         WHERE ri.ingredientid = ANY($2) AND other.ingredientid = ANY($2)
+         AND other.recipeid = ri.recipeid AND other.ingredientid < ri.ingredientid`,
+		`UPDATE recipeingredients SET ingredientid = $1 WHERE ingredientid = ANY($2)`,
+		`UPDATE ingredient_aliases SET ingredientid = $1 WHERE ingredientid = ANY($2)`,
+		`INSERT INTO ingredient_aliases (alias, ingredientid)
+         SELECT DISTINCT LOWER(s.ingredientname), $1::integer
+         FROM ingredients s, ingredients t
+         WHERE s.ingredientid = ANY($2) AND t.ingredientid = $1
+         AND LOWER(s.ingredientname) <> LOWER(t.ingredientname)
+         ON CONFLICT (alias) DO UPDATE SET ingredientid = EXCLUDED.ingredientid`,
+		`DELETE FROM ingredients WHERE ingredientid = ANY($2)`,
+	}
+	for _, statement := range statements {
+		_, err = tx.ExecContext(ctx, statement, targetID, sourceIDs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	query := `SELECT` + catalogIngredientColumns + `
+        FROM ingredients i
+        WHERE i.ingredientid = $1`
+
+	ingredient, err := scanCatalogIngredient(tx.QueryRowContext(ctx, query, targetID))
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	m.suggestions.invalidate()
+	return ingredient, nil
+}
+
+// ListNames returns every ingredient name in alphabetical order.
+func (m IngredientModel) ListNames() ([]string, error) {
+	query := `SELECT DISTINCT ingredientname FROM ingredients ORDER BY ingredientname`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := make([]string, 0)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
+
+func replaceAliases(ctx context.Context, db queryer, ingredientID int64, aliases []string) error {
+	_, err := db.ExecContext(ctx, `DELETE FROM ingredient_aliases WHERE ingredientid = $1`, ingredientID)
+	if err != nil {
+		return err
+	}
+
+	for _, alias := range aliases {
+		_, err = db.ExecContext(ctx, `INSERT INTO ingredient_aliases (alias, ingredientid) VALUES ($1, $2)`, alias, ingredientID)
+		if err != nil {
+			switch {
+			case isUniqueViolation(err):
+				return ErrDuplicateIngredient
+			default:
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// likePrefix lower-cases s and escapes the LIKE wildcards in it, so that it can be
+// used as a literal prefix.
+func likePrefix(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -1,44 +1,196 @@
 package data
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 var ErrInvalidRuntimeFormat = errors.New("invalid minutes format")
 
+// Mins is a duration in whole minutes. It is read from JSON as a number of minutes or
+// as a string holding any of
+//
+//	45, "45", "45 mins", "1 min"              plain minutes
+//	"1 hr 30 mins", "1 hour, 30 minutes"      human text
+//	"1h30m", "90m"                            Go durations
+//	"PT1H30M", "P1DT2H"                       ISO 8601 durations
+//
+// Anything finer than a minute is rounded to the nearest minute. Mins is written in the
+// "N mins" form unless the recipe holding it is given another DurationFormat.
 type Mins int32
 
-func (m Mins) MarshalJSON() ([]byte, error) {
-	jsonValue := fmt.Sprintf("%d mins", m)
-	quotedJSONValue := strconv.Quote(jsonValue)
-	return []byte(quotedJSONValue), nil
+// DurationFormat selects how Mins values are written in a response.
+type DurationFormat string
+
+const (
+	DurationFormatMins    DurationFormat = "mins"    // "90 mins"
+	DurationFormatHuman   DurationFormat = "human"   // "1 hr 30 mins"
+	DurationFormatISO8601 DurationFormat = "iso8601" // "PT1H30M"
+	DurationFormatMinutes DurationFormat = "minutes" // 90
+)
+
+// DurationFormats lists the permitted duration formats; the first is the default.
+var DurationFormats = []DurationFormat{DurationFormatMins, DurationFormatHuman, DurationFormatISO8601, DurationFormatMinutes}
+
+var (
+	isoDurationRX   = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+(?:\.\d+)?)H)?(?:(\d+(?:\.\d+)?)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+	humanDurationRX = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*(days?|d|hours?|hrs?|h|minutes?|mins?|m|seconds?|secs?|s)\b`)
+	humanJoinerRX   = regexp.MustCompile(`^(?:[\s,]|and)*$`)
+)
+
+var humanUnits = map[string]float64{
+	"d": 24 * 60, "day": 24 * 60, "days": 24 * 60,
+	"h": 60, "hr": 60, "hrs": 60, "hour": 60, "hours": 60,
+	"m": 1, "min": 1, "mins": 1, "minute": 1, "minutes": 1,
+	"s": 1.0 / 60, "sec": 1.0 / 60, "secs": 1.0 / 60, "second": 1.0 / 60, "seconds": 1.0 / 60,
 }
 
-func (m *Mins) UnmarshalJSON(jsonValue []byte) error {
+// ParseMins reads a duration in any of the forms accepted by Mins.
+func ParseMins(s string) (Mins, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, ErrInvalidRuntimeFormat
+	}
 
-	unquotedJSONValue, err := strconv.Unquote(string(jsonValue))
-	if err != nil {
-		return ErrInvalidRuntimeFormat
+	if i, err := strconv.ParseInt(s, 10, 32); err == nil {
+		return minsFromFloat(float64(i))
 	}
 
-	fmt.Printf("Unquoted JSON value: %s\n", unquotedJSONValue)
+	if match := isoDurationRX.FindStringSubmatch(strings.ToUpper(s)); match != nil && s != "P" && !strings.HasSuffix(strings.ToUpper(s), "T") {
+		var minutes float64
+		for i, scale := range []float64{24 * 60, 60, 1, 1.0 / 60} {
+			if match[i+1] == "" {
+				continue
+			}
+			f, err := strconv.ParseFloat(match[i+1], 64)
+			if err != nil {
+				return 0, ErrInvalidRuntimeFormat
+			}
+			minutes += f * scale
+		}
+		return minsFromFloat(minutes)
+	}
 
-	parts := strings.Split(unquotedJSONValue, " ")
-	fmt.Printf("Parts after splitting unquoted JSON value: %v\n", parts)
+	if d, err := time.ParseDuration(s); err == nil {
+		return minsFromFloat(d.Minutes())
+	}
 
-	if len(parts) != 2 || parts[1] != "mins" {
-		return ErrInvalidRuntimeFormat
+	return parseHumanMins(strings.ToLower(s))
+}
+
+// parseHumanMins reads text such as "1 hr 30 mins" or "2 hours and 5 minutes". Every
+// part of the text must be a number followed by a unit.
+func parseHumanMins(s string) (Mins, error) {
+	matches := humanDurationRX.FindAllStringSubmatchIndex(s, -1)
+	if matches == nil {
+		return 0, ErrInvalidRuntimeFormat
 	}
 
-	i, err := strconv.ParseInt(parts[0], 10, 32)
+	var minutes float64
+	last := 0
+	for _, m := range matches {
+		if !humanJoinerRX.MatchString(s[last:m[0]]) {
+			return 0, ErrInvalidRuntimeFormat
+		}
+		f, err := strconv.ParseFloat(s[m[2]:m[3]], 64)
+		if err != nil {
+			return 0, ErrInvalidRuntimeFormat
+		}
+		minutes += f * humanUnits[s[m[4]:m[5]]]
+		last = m[1]
+	}
+	if !humanJoinerRX.MatchString(s[last:]) {
+		return 0, ErrInvalidRuntimeFormat
+	}
+	return minsFromFloat(minutes)
+}
+
+func minsFromFloat(minutes float64) (Mins, error) {
+	minutes = math.Round(minutes)
+	if minutes < 0 || minutes > math.MaxInt32 {
+		return 0, ErrInvalidRuntimeFormat
+	}
+	return Mins(minutes), nil
+}
+
+func (m Mins) MarshalJSON() ([]byte, error) {
+	return DurationFormatMins.marshal(m)
+}
+
+func (m *Mins) UnmarshalJSON(jsonValue []byte) error {
+	var value any
+	err := json.Unmarshal(jsonValue, &value)
 	if err != nil {
-		fmt.Printf("Error parsing integer from parts: %v\n", err)
+		return ErrInvalidRuntimeFormat
+	}
 
+	var parsed Mins
+	switch value := value.(type) {
+	case float64:
+		if value != math.Trunc(value) {
+			return ErrInvalidRuntimeFormat
+		}
+		parsed, err = minsFromFloat(value)
+	case string:
+		parsed, err = ParseMins(value)
+	default:
 		return ErrInvalidRuntimeFormat
 	}
-	*m = Mins(i)
+	if err != nil {
+		return err
+	}
+
+	*m = parsed
 	return nil
 }
+
+// Human returns m as text such as "1 hr 30 mins".
+func (m Mins) Human() string {
+	hours, minutes := int(m)/60, int(m)%60
+	var parts []string
+	switch {
+	case hours == 1:
+		parts = append(parts, "1 hr")
+	case hours > 1:
+		parts = append(parts, fmt.Sprintf("%d hrs", hours))
+	}
+	switch {
+	case minutes == 1:
+		parts = append(parts, "1 min")
+	case minutes > 1 || hours == 0:
+		parts = append(parts, fmt.Sprintf("%d mins", minutes))
+	}
+	return strings.Join(parts, " ")
+}
+
+// ISO8601 returns m as an ISO 8601 duration such as "PT1H30M".
+func (m Mins) ISO8601() string {
+	hours, minutes := int(m)/60, int(m)%60
+	s := "PT"
+	if hours > 0 {
+		s += fmt.Sprintf("%dH", hours)
+	}
+	if minutes > 0 || hours == 0 {
+		s += fmt.Sprintf("%dM", minutes)
+	}
+	return s
+}
+
+func (f DurationFormat) marshal(m Mins) ([]byte, error) {
+	switch f {
+	case DurationFormatHuman:
+		return json.Marshal(m.Human())
+	case DurationFormatISO8601:
+		return json.Marshal(m.ISO8601())
+	case DurationFormatMinutes:
+		return json.Marshal(int32(m))
+	default:
+		return json.Marshal(fmt.Sprintf("%d mins", m))
+	}
+}
@@ -0,0 +1,155 @@
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseMins(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Mins
+		err   bool
+	}{
+		{input: "45", want: 45},
+		{input: " 45 ", want: 45},
+		{input: "0", want: 0},
+		{input: "45 mins", want: 45},
+		{input: "1 min", want: 1},
+		{input: "1 hr 30 mins", want: 90},
+		{input: "1 hour, 30 minutes", want: 90},
+		{input: "2 hours and 5 minutes", want: 125},
+		{input: "1.5 hours", want: 90},
+		{input: "90 seconds", want: 2},
+		{input: "1h30m", want: 90},
+		{input: "90m", want: 90},
+		{input: "45s", want: 1},
+		{input: "PT1H30M", want: 90},
+		{input: "pt45m", want: 45},
+		{input: "P1DT2H", want: 1560},
+		{input: "P1D", want: 1440},
+		{input: "PT0.5H", want: 30},
+		{input: "PT90S", want: 2},
+		{input: "-5", err: true},
+		{input: "-1h", err: true},
+		{input: "-5 mins", err: true},
+		{input: "", err: true},
+		{input: "   ", err: true},
+		{input: "P", err: true},
+		{input: "PT", err: true},
+		{input: "P1DT", err: true},
+		{input: "soon", err: true},
+		{input: "mins", err: true},
+		{input: "1 hr or so", err: true},
+		{input: "about 30 mins", err: true},
+		{input: "1 fortnight", err: true},
+		{input: "99999999999", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseMins(tt.input)
+			if tt.err {
+				if !errors.Is(err, ErrInvalidRuntimeFormat) {
+					t.Errorf("ParseMins(%q) = %d, %v, want ErrInvalidRuntimeFormat", tt.input, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("ParseMins(%q) = %d, %v, want %d", tt.input, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMinsUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Mins
+		err   bool
+	}{
+		{input: `45`, want: 45},
+		{input: `0`, want: 0},
+		{input: `45.0`, want: 45},
+		{input: `"45"`, want: 45},
+		{input: `"45 mins"`, want: 45},
+		{input: `"1h30m"`, want: 90},
+		{input: `"PT1H30M"`, want: 90},
+		{input: `"1 hr 30 mins"`, want: 90},
+		{input: `-5`, err: true},
+		{input: `"-5"`, err: true},
+		{input: `45.5`, err: true},
+		{input: `3000000000`, err: true},
+		{input: `""`, err: true},
+		{input: `"soon"`, err: true},
+		{input: `null`, err: true},
+		{input: `true`, err: true},
+		{input: `[45]`, err: true},
+		{input: `{"mins": 45}`, err: true},
+		{input: `45 mins`, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got := Mins(7)
+			err := got.UnmarshalJSON([]byte(tt.input))
+			if tt.err {
+				if !errors.Is(err, ErrInvalidRuntimeFormat) {
+					t.Errorf("UnmarshalJSON(%s) = %v, want ErrInvalidRuntimeFormat", tt.input, err)
+				}
+				if got != 7 {
+					t.Errorf("UnmarshalJSON(%s) changed the value to %d on failure", tt.input, got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("UnmarshalJSON(%s) = %d, %v, want %d", tt.input, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDurationFormatMarshal(t *testing.T) {
+	tests := []struct {
+		value                         Mins
+		minutes, human, iso8601, mins string
+	}{
+		{value: 0, minutes: `0`, human: `"0 mins"`, iso8601: `"PT0M"`, mins: `"0 mins"`},
+		{value: 1, minutes: `1`, human: `"1 min"`, iso8601: `"PT1M"`, mins: `"1 mins"`},
+		{value: 45, minutes: `45`, human: `"45 mins"`, iso8601: `"PT45M"`, mins: `"45 mins"`},
+		{value: 60, minutes: `60`, human: `"1 hr"`, iso8601: `"PT1H"`, mins: `"60 mins"`},
+		{value: 61, minutes: `61`, human: `"1 hr 1 min"`, iso8601: `"PT1H1M"`, mins: `"61 mins"`},
+		{value: 90, minutes: `90`, human: `"1 hr 30 mins"`, iso8601: `"PT1H30M"`, mins: `"90 mins"`},
+		{value: 1560, minutes: `1560`, human: `"26 hrs"`, iso8601: `"PT26H"`, mins: `"1560 mins"`},
+	}
+
+	for _, tt := range tests {
+		for format, want := range map[DurationFormat]string{
+			DurationFormatMinutes: tt.minutes,
+			DurationFormatHuman:   tt.human,
+			DurationFormatISO8601: tt.iso8601,
+			DurationFormatMins:    tt.mins,
+			"":                    tt.mins,
+		} {
+			got, err := format.marshal(tt.value)
+			if err != nil || string(got) != want {
+				t.Errorf("%q.marshal(%d) = %s, %v, want %s", format, tt.value, got, err, want)
+			}
+		}
+
+		got, err := json.Marshal(tt.value)
+		if err != nil || string(got) != tt.mins {
+			t.Errorf("json.Marshal(%d) = %s, %v, want %s", tt.value, got, err, tt.mins)
+		}
+
+		// Every format reads back as the same number of minutes.
+		for _, format := range DurationFormats {
+			b, _ := format.marshal(tt.value)
+			var back Mins
+			if err := json.Unmarshal(b, &back); err != nil || back != tt.value {
+				t.Errorf("%q: %s read back as %d, %v, want %d", format, b, back, err, tt.value)
+			}
+		}
+	}
+}
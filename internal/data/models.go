@@ -1,23 +1,65 @@
 package data
 
 import (
+	"context"
 	"database/sql"
 	"errors"
-)
 
+	"github.com/jackc/pgx/v5/pgconn"
+)
 
 var (
 	ErrRecordNotFound = errors.New("record not found")
 )
 
-
 type Models struct {
-	Recipes RecipeModel
+	Recipes     RecipeModel
+	Cuisines    CuisineModel
+	Ingredients IngredientModel
+	Tags        TagModel
+	Favorites   FavoriteModel
+	Ratings     RatingModel
+	Backups     BackupModel
+	Audit       AuditModel
+	Authors     AuthorModel
+	Similarity  SimilarityModel
+	Health      HealthModel
 }
 
-
 func NewModels(db *sql.DB) Models {
+	// The suggestion cache is shared with the recipe model, since adding or removing
+	// recipes changes how popular each ingredient is.
+	suggestions := newSuggestionCache()
+
 	return Models{
-		Recipes: RecipeModel{DB: db},
+		Recipes:     RecipeModel{DB: db, suggestions: suggestions},
+		Cuisines:    CuisineModel{DB: db},
+		Ingredients: IngredientModel{DB: db, suggestions: suggestions},
+		Tags:        TagModel{DB: db},
+		Favorites:   FavoriteModel{DB: db},
+		Ratings:     RatingModel{DB: db},
+		Backups:     BackupModel{DB: db},
+		Audit:       AuditModel{DB: db},
+		Authors:     AuthorModel{DB: db},
+		Similarity:  SimilarityModel{DB: db},
+		Health:      HealthModel{DB: db},
 	}
 }
+
+// queryer is satisfied by both *sql.DB and *sql.Tx, so helpers can run inside or
+// outside a transaction.
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503"
+}
@@ -0,0 +1,116 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// testDSNVar names the environment variable holding the database the tests which need
+// Postgres run against. They change its data, so it must be a migrated database kept
+// for testing.
+const testDSNVar = "RECIPE_TEST_DB_DSN"
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv(testDSNVar)
+	if dsn == "" {
+		t.Skipf("set %s to a migrated, disposable database to run this test", testDSNVar)
+	}
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// TestPurge trashes a recipe with an image, ingredients, steps, tags, a fork and a
+// favorite, backdates it past the retention period, and checks that purging it leaves
+// nothing behind.
+func TestPurge(t *testing.T) {
+	db := openTestDB(t)
+	models := NewModels(db)
+
+	cuisine := &Cuisine{Name: "Purge Test", Slug: "purge-test"}
+	err := models.Cuisines.Insert(cuisine)
+	if err != nil && !errors.Is(err, ErrDuplicateCuisine) {
+		t.Fatal(err)
+	}
+
+	recipe := &Recipe{
+		Title:        "Purged Soup",
+		Instructions: "Chop. Simmer.",
+		Steps:        []Step{{Position: 1, Text: "Chop."}, {Position: 2, Text: "Simmer.", Duration: 30}},
+		PrepTime:     10,
+		CookTime:     30,
+		Difficulty:   DifficultyEasy,
+		CuisineName:  cuisine.Name,
+		Ingredients:  []Ingredient{{IngredientName: "leek", Quantity: 2}, {IngredientName: "stock", Quantity: 1, Unit: "l"}},
+		Tags:         []string{"purge-test"},
+		ImageLink:    "https://example.com/soup.jpg",
+		Status:       StatusPublished,
+	}
+	if err := models.Recipes.Insert(recipe, "purger"); err != nil {
+		t.Fatal(err)
+	}
+	fork := &Recipe{
+		Title:        "Purged Soup, Spicier",
+		Instructions: "Chop. Simmer with chilli.",
+		PrepTime:     10,
+		CookTime:     30,
+		Difficulty:   DifficultyEasy,
+		CuisineName:  cuisine.Name,
+		ParentID:     recipe.ID,
+	}
+	if err := models.Recipes.Insert(fork, "purger"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := models.Favorites.Add("purger", int64(recipe.ID)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := models.Recipes.Delete(int64(recipe.ID)); err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`UPDATE recipes SET deleted_at = NOW() - INTERVAL '2 days' WHERE recipeid = $1`, recipe.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	purged, err := models.Recipes.Purge(24 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged < 1 {
+		t.Fatalf("Purge() = %d, want at least 1", purged)
+	}
+
+	for _, table := range []string{
+		"recipes", "recipeingredients", "recipe_images", "recipe_steps", "recipe_tags",
+		"recipe_revisions", "recipe_favorites", "recipe_ratings",
+	} {
+		var count int
+		err := db.QueryRow(`SELECT COUNT(*) FROM `+table+` WHERE recipeid = $1`, recipe.ID).Scan(&count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Errorf("%d rows of %s are left for the purged recipe", count, table)
+		}
+	}
+
+	var parentID sql.NullInt64
+	err = db.QueryRow(`SELECT parent_id FROM recipes WHERE recipeid = $1`, fork.ID).Scan(&parentID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parentID.Valid {
+		t.Errorf("the fork still has the purged recipe %d as its parent", parentID.Int64)
+	}
+}
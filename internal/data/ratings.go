@@ -0,0 +1,65 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"recipe.athif.com/internal/validator"
+)
+
+// Rating is an actor's score for a recipe, from 1 to 5.
+type Rating struct {
+	RecipeID  int       `json:"recipe_id"`
+	Rating    int       `json:"rating"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func ValidateRating(v *validator.Validator, rating int) {
+	v.Check(rating >= 1 && rating <= 5, "rating", "must be between 1 and 5")
+}
+
+type RatingModel struct {
+	DB *sql.DB
+}
+
+// Set records the actor's rating for the recipe, replacing any earlier one.
+func (m RatingModel) Set(actor string, recipeID int64, rating int) (*Rating, error) {
+	query := `
+        INSERT INTO recipe_ratings (actor, recipeid, rating)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (actor, recipeid) DO UPDATE SET rating = EXCLUDED.rating, updated_at = NOW()
+        RETURNING recipeid, rating, updated_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var r Rating
+	err := m.DB.QueryRowContext(ctx, query, actor, recipeID, rating).Scan(&r.RecipeID, &r.Rating, &r.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Remove deletes the actor's rating for the recipe, returning ErrRecordNotFound if
+// there wasn't one.
+func (m RatingModel) Remove(actor string, recipeID int64) error {
+	query := `DELETE FROM recipe_ratings WHERE actor = $1 AND recipeid = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, actor, recipeID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"recipe.athif.com/internal/validator"
+)
+
+func ValidateIngredients(v *validator.Validator, ingredients []Ingredient) {
+	v.Check(len(ingredients) <= 100, "ingredients", "must not contain more than 100 ingredients")
+	names := make([]string, len(ingredients))
+	for i, ingredient := range ingredients {
+		names[i] = strings.ToLower(strings.TrimSpace(ingredient.IngredientName))
+		if names[i] == "" {
+			v.AddError("ingredients", "must not contain an ingredient without a name")
+		}
+		if len(ingredient.IngredientName) > 100 {
+			v.AddError("ingredients", "must not contain a name more than 100 bytes long")
+		}
+		if ingredient.Quantity < 0 {
+			v.AddError("ingredients", "must not contain a negative quantity")
+		}
+		if len(ingredient.Unit) > 50 {
+			v.AddError("ingredients", "must not contain a unit more than 50 bytes long")
+		}
+	}
+	v.Check(validator.Unique(names), "ingredients", "must not contain the same ingredient twice")
+}
+
+// replaceIngredients overwrites the ingredient lines of a recipe. Each name is matched
+// against the catalog, including aliases, and a new catalog entry is created for names
+// that aren't found. The lines are updated with the catalog ID and name.
+func replaceIngredients(ctx context.Context, db queryer, recipeID int, ingredients []Ingredient) error {
+	_, err := db.ExecContext(ctx, `DELETE FROM recipeingredients WHERE recipeid = $1`, recipeID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+        INSERT INTO recipeingredients (recipeid, ingredientid, quantity, unit)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (recipeid, ingredientid) DO NOTHING`
+
+	for i := range ingredients {
+		ingredient := &ingredients[i]
+		ingredient.IngredientID, ingredient.IngredientName, err = resolveIngredient(ctx, db, ingredient.IngredientName)
+		if err != nil {
+			return err
+		}
+		_, err = db.ExecContext(ctx, query, recipeID, ingredient.IngredientID, ingredient.Quantity, strings.TrimSpace(ingredient.Unit))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveIngredient returns the catalog entry for name, matching it case-insensitively
+// against ingredient names and then aliases, and creating the entry if neither matches.
+func resolveIngredient(ctx context.Context, db queryer, name string) (int64, string, error) {
+	name = strings.TrimSpace(name)
+
+	query := `
+        SELECT i.ingredientid, i.ingredientname
+        FROM ingredients i
+        LEFT JOIN ingredient_aliases a ON a.ingredientid = i.ingredientid AND a.alias = LOWER($1)
+        WHERE LOWER(i.ingredientname) = LOWER($1) OR a.alias IS NOT NULL
+        ORDER BY LOWER(i.ingredientname) = LOWER($1) DESC
+        LIMIT 1`
+
+	var id int64
+	var canonical string
+	err := db.QueryRowContext(ctx, query, name).Scan(&id, &canonical)
+	switch {
+	case err == nil:
+		return id, canonical, nil
+	case !errors.Is(err, sql.ErrNoRows):
+		return 0, "", err
+	}
+
+	err = db.QueryRowContext(ctx, `INSERT INTO ingredients (ingredientname) VALUES ($1) RETURNING ingredientid`, name).Scan(&id)
+	if err != nil {
+		return 0, "", err
+	}
+	return id, name, nil
+}
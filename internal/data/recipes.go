@@ -3,6 +3,7 @@ package data
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
@@ -13,7 +14,7 @@ import (
 )
 
 type Ingredient struct {
-	// IngredientID   int64   `json:"ingredient_id"`
+	IngredientID   int64   `json:"ingredient_id"`
 	IngredientName string  `json:"ingredient_name"`
 	Quantity       float32 `json:"quantity"`
 	Unit           string  `json:"unit"`
@@ -22,104 +23,406 @@ type Recipe struct {
 	ID           int          `json:"id"`
 	Title        string       `json:"title"`
 	Instructions string       `json:"instructions"`
+	Steps        []Step       `json:"steps"`
 	PrepTime     Mins         `json:"prep_time"`
 	CookTime     Mins         `json:"cook_time"`
-	Difficulty   string       `json:"difficulty"`
+	Difficulty   Difficulty   `json:"difficulty"`
+	TotalTime    Mins         `json:"total_time"`
 	CuisineName  string       `json:"cuisine_name"`
-	Ingredients  []Ingredient `json:"ingredients"` 
-	ImageLink    string
+	Ingredients  []Ingredient `json:"ingredients"`
+	Tags         []string     `json:"tags"`
+	ImageLink    string       `json:"image_link"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+	Status       Status       `json:"status"`
+	// ReviewReason is the moderator's reason for rejecting the recipe.
+	ReviewReason string `json:"review_reason,omitempty"`
+	// PublishAt is when the recipe was, or is scheduled to be, published.
+	PublishAt *time.Time `json:"publish_at,omitempty"`
+	// ParentID is the recipe this one was forked from, if any.
+	ParentID int `json:"parent_id,omitempty"`
+	// Author is who created the recipe. It decides who may see the recipe before it is
+	// published, and is never sent to clients.
+	Author string `json:"-"`
+	// durationFormat is how the prep, cook and step times are written to JSON.
+	durationFormat DurationFormat
+}
+
+// SetDurationFormat chooses how the recipe's times are written when it is encoded as
+// JSON. The zero value gives the default "N mins" form.
+func (r *Recipe) SetDurationFormat(format DurationFormat) {
+	r.durationFormat = format
+}
+
+// MarshalJSON writes the recipe with its times in the chosen DurationFormat, keeping
+// the fields in the same order as the struct.
+func (r Recipe) MarshalJSON() ([]byte, error) {
+	var steps []Step
+	if r.Steps != nil {
+		steps = make([]Step, len(r.Steps))
+		for i, step := range r.Steps {
+			step.durationFormat = r.durationFormat
+			steps[i] = step
+		}
+	}
+
+	times := make([]json.RawMessage, 3)
+	for i, m := range []Mins{r.PrepTime, r.CookTime, r.TotalTime} {
+		js, err := r.durationFormat.marshal(m)
+		if err != nil {
+			return nil, err
+		}
+		times[i] = js
+	}
+
+	return json.Marshal(struct {
+		ID           int             `json:"id"`
+		Title        string          `json:"title"`
+		Instructions string          `json:"instructions"`
+		Steps        []Step          `json:"steps"`
+		PrepTime     json.RawMessage `json:"prep_time"`
+		CookTime     json.RawMessage `json:"cook_time"`
+		Difficulty   Difficulty      `json:"difficulty"`
+		TotalTime    json.RawMessage `json:"total_time"`
+		CuisineName  string          `json:"cuisine_name"`
+		Ingredients  []Ingredient    `json:"ingredients"`
+		Tags         []string        `json:"tags"`
+		ImageLink    string          `json:"image_link"`
+		UpdatedAt    time.Time       `json:"updated_at"`
+		Status       Status          `json:"status"`
+		ReviewReason string          `json:"review_reason,omitempty"`
+		PublishAt    *time.Time      `json:"publish_at,omitempty"`
+		ParentID     int             `json:"parent_id,omitempty"`
+	}{r.ID, r.Title, r.Instructions, steps, times[0], times[1], r.Difficulty, times[2], r.CuisineName, r.Ingredients, r.Tags, r.ImageLink, r.UpdatedAt,
+		r.Status, r.ReviewReason, r.PublishAt, r.ParentID})
 }
 
 func ValidateRecipe(v *validator.Validator, recipe *Recipe) {
 	v.Check(recipe.Title != "", "title", "must be provided")
 	v.Check(len(recipe.Title) <= 500, "title", "must not be more than 500 bytes long")
-	v.Check(recipe.PrepTime != 0, "preparation_time", "must be provided")
-	v.Check(recipe.PrepTime > 0, "preparation_time", "must be a positive integer")
-	v.Check(recipe.CookTime != 0, "cooking_time", "must be provided")
-	v.Check(recipe.CookTime > 0, "cooking_time", "must be a positive integer")
+	v.Check(recipe.PrepTime != 0, "prep_time", "must be provided")
+	v.Check(recipe.PrepTime > 0, "prep_time", "must be a positive integer")
+	v.Check(recipe.CookTime != 0, "cook_time", "must be provided")
+	v.Check(recipe.CookTime > 0, "cook_time", "must be a positive integer")
 	v.Check(recipe.CuisineName != "", "cuisine_name", "must be provided")
 	v.Check(recipe.Difficulty != "", "difficulty", "must be provided")
-	v.Check(recipe.Difficulty != "", "instructions", "must be provided")
+	v.Check(validator.PermittedValue(recipe.Difficulty, Difficulties...), "difficulty", "must be one of Easy, Medium or Advanced")
+	v.Check(recipe.Instructions != "" || len(recipe.Steps) > 0, "instructions", "must be provided")
+	ValidateSteps(v, recipe.Steps)
+	ValidateTags(v, recipe.Tags)
+	ValidateIngredients(v, recipe.Ingredients)
+}
+
+// RecipeCriteria narrows down a recipe listing. Zero values are ignored. The cuisine may
+// be selected either by CuisineID or by Cuisine, which matches a cuisine slug or name.
+// Tags must all be present on a recipe, or any one of them when AnyTag is set. Status
+// and Author select recipes in the publishing workflow, so public listings must set
+// Status to StatusPublished. ParentID selects the forks of a recipe.
+type RecipeCriteria struct {
+	Title     string
+	CuisineID int
+	Cuisine   string
+	Tags      []string
+	AnyTag    bool
+	Status    Status
+	Author    string
+	ParentID  int
+	// ExcludeScheduled leaves out recipes which are pending review only because they
+	// were approved for a later publication.
+	ExcludeScheduled bool
+	RecipeLimits
+}
+
+// RecipeLimits bounds how hard and how long a recipe may be, for both listing and
+// searching recipes. Zero values are ignored.
+type RecipeLimits struct {
+	Difficulty   DifficultyRange
+	MaxTotalTime Mins
+	MaxPrepTime  Mins
+	MaxCookTime  Mins
+}
+
+// totalTime is the SQL equivalent of Recipe.TotalTime.
+const totalTime = `(r.preparationtime + r.cookingtime)`
+
+// conditions returns the limits as SQL conditions on the recipes table r, numbering
+// the placeholders from $n, along with the arguments for them.
+func (l RecipeLimits) conditions(n int) (string, []any) {
+	minDifficulty, maxDifficulty := l.Difficulty.bounds()
+	query := fmt.Sprintf(`%s BETWEEN $%d AND $%d
+    AND (%s <= $%d OR $%d = 0)
+    AND (r.preparationtime <= $%d OR $%d = 0)
+    AND (r.cookingtime <= $%d OR $%d = 0)`,
+		difficultyRank, n, n+1, totalTime, n+2, n+2, n+3, n+3, n+4, n+4)
+	args := []any{minDifficulty, maxDifficulty, l.MaxTotalTime, l.MaxPrepTime, l.MaxCookTime}
+	return query, args
 }
 
 type RecipeModel struct {
-	DB *sql.DB
+	DB          *sql.DB
+	suggestions *suggestionCache
+}
+
+// recipeColumns are the columns read by scanRecipe. Queries selecting them must join
+// cuisine as c and LEFT JOIN recipe_images as img.
+const recipeColumns = `
+    r.recipeid, r.recipename, r.instructions, r.preparationtime, r.cookingtime, r.difficultylevel,
+    c.cuisinename, COALESCE(img.imagelink, ''), r.updated_at,
+    r.status, r.review_reason, r.publish_at, r.author, COALESCE(r.parent_id, 0)`
+
+func scanRecipe(row interface{ Scan(...any) error }, dest ...any) (*Recipe, error) {
+	var recipe Recipe
+	dest = append(dest,
+		&recipe.ID,
+		&recipe.Title,
+		&recipe.Instructions,
+		&recipe.PrepTime,
+		&recipe.CookTime,
+		&recipe.Difficulty,
+		&recipe.CuisineName,
+		&recipe.ImageLink,
+		&recipe.UpdatedAt,
+		&recipe.Status,
+		&recipe.ReviewReason,
+		&recipe.PublishAt,
+		&recipe.Author,
+		&recipe.ParentID,
+	)
+	err := row.Scan(dest...)
+	if err != nil {
+		return nil, err
+	}
+	recipe.TotalTime = recipe.PrepTime + recipe.CookTime
+	return &recipe, nil
+}
+
+// Insert saves a new recipe, recording it as revision 1 by author.
+func (r RecipeModel) Insert(recipe *Recipe, author string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = insertRecipe(ctx, tx, recipe, author)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	r.suggestions.invalidate()
+	return nil
+}
+
+// InsertBatch inserts the recipes in a single transaction and returns an error for
+// each recipe, which is nil for those that were inserted. A recipe which fails doesn't
+// stop the others being inserted. When dryRun is set the transaction is rolled back,
+// so the errors report what would have happened without changing anything.
+func (r RecipeModel) InsertBatch(recipes []*Recipe, dryRun bool, author string) ([]error, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	errs := make([]error, len(recipes))
+	for i, recipe := range recipes {
+		_, err = tx.ExecContext(ctx, `SAVEPOINT insert_recipe`)
+		if err != nil {
+			return nil, err
+		}
+
+		errs[i] = insertRecipe(ctx, tx, recipe, author)
+		if errs[i] != nil {
+			recipe.ID = 0
+			_, err = tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT insert_recipe`)
+		} else {
+			_, err = tx.ExecContext(ctx, `RELEASE SAVEPOINT insert_recipe`)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if dryRun {
+		return errs, nil
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	r.suggestions.invalidate()
+	return errs, nil
 }
 
-func (r RecipeModel) Insert(recipe *Recipe) error {
+// insertRecipe inserts a recipe along with its steps, tags and ingredients, and records
+// it as the first revision. Recipes without a status are saved as drafts, and those
+// saved as published are stamped with the time they were published.
+func insertRecipe(ctx context.Context, tx queryer, recipe *Recipe, author string) error {
+	cuisine, err := lookupCuisine(ctx, tx, recipe.CuisineName)
+	if err != nil {
+		return err
+	}
+
+	if recipe.Status == "" {
+		recipe.Status = StatusDraft
+	}
+	recipe.Author = author
+
 	query := `
-        INSERT INTO recipes (recipename, instructions, preparationtime, cookingtime, difficultylevel, cuisinename)
-        SELECT $1, $2, $3, $4, $5, cuisinename
-        FROM cuisine
-        WHERE cuisinename = $6
-        RETURNING recipeid
+        INSERT INTO recipes (recipename, instructions, preparationtime, cookingtime, difficultylevel, cuisineid, status, author, publish_at, parent_id)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, CASE WHEN $7 = 'published' THEN NOW() END, NULLIF($9, 0))
+        RETURNING recipeid, updated_at, publish_at
     `
 
-	args := []interface{}{recipe.Title, recipe.Instructions, recipe.PrepTime, recipe.CookTime, recipe.Difficulty, recipe.CuisineName}
+	args := []interface{}{recipe.Title, recipe.Instructions, recipe.PrepTime, recipe.CookTime, recipe.Difficulty, cuisine.ID, recipe.Status, author, recipe.ParentID}
 
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&recipe.ID, &recipe.UpdatedAt, &recipe.PublishAt)
+	if err != nil {
+		return err
+	}
+
+	err = replaceImage(ctx, tx, recipe.ID, recipe.ImageLink)
+	if err != nil {
+		return err
+	}
+
+	err = replaceSteps(ctx, tx, recipe.ID, recipe.Steps)
+	if err != nil {
+		return err
+	}
+
+	err = replaceTags(ctx, tx, recipe.ID, recipe.Tags)
+	if err != nil {
+		return err
+	}
+
+	if recipe.Ingredients == nil {
+		recipe.Ingredients = []Ingredient{}
+	}
+	err = replaceIngredients(ctx, tx, recipe.ID, recipe.Ingredients)
+	if err != nil {
+		return err
+	}
+
+	recipe.CuisineName = cuisine.Name
+	recipe.TotalTime = recipe.PrepTime + recipe.CookTime
+	return writeRevision(ctx, tx, recipe, author)
+}
+
+// replaceImage sets the recipe's image, removing it when link is empty.
+func replaceImage(ctx context.Context, db queryer, recipeID int, link string) error {
+	_, err := db.ExecContext(ctx, `DELETE FROM recipe_images WHERE recipeid = $1`, recipeID)
+	if err != nil || link == "" {
+		return err
+	}
+	_, err = db.ExecContext(ctx, `INSERT INTO recipe_images (recipeid, imagelink) VALUES ($1, $2)`, recipeID, link)
+	return err
+}
+
+func (r RecipeModel) Get(id int64) (*Recipe, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	return r.DB.QueryRowContext(ctx, query, args...).Scan(&recipe.ID)
+	return getRecipe(ctx, r.DB, id)
 }
 
-func (r RecipeModel) Get(id int64) (*Recipe, error) {
+func getRecipe(ctx context.Context, db queryer, id int64) (*Recipe, error) {
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
 
 	query := `
-    SELECT r.recipeid, r.recipename, r.instructions, r.preparationtime, r.cookingtime, r.difficultylevel, c.cuisinename, i.ingredientname, ri.quantity, ri.unit, img.imagelink
+    SELECT` + recipeColumns + `
     FROM recipes r
     INNER JOIN cuisine c ON r.cuisineid = c.cuisineid
-    INNER JOIN recipeingredients ri ON r.recipeid = ri.recipeid
-    INNER JOIN ingredients i ON ri.ingredientid = i.ingredientid
-	INNER JOIN recipe_images img ON r.recipeid = img.recipeid
-    WHERE r.recipeid = $1
+    LEFT JOIN recipe_images img ON r.recipeid = img.recipeid
+    WHERE r.recipeid = $1 AND r.deleted_at IS NULL
     `
 
+	recipe, err := scanRecipe(db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	err = loadRecipeDetails(ctx, db, []*Recipe{recipe})
+	if err != nil {
+		return nil, err
+	}
+
+	return recipe, nil
+}
+
+// Update saves the recipe and records the result as a new revision by author. When
+// needsReview is set, a published recipe goes back to pending review, so that edits
+// reach the public only once a moderator has approved them.
+func (r RecipeModel) Update(recipe *Recipe, author string, needsReview bool) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	rows, err := r.DB.QueryContext(ctx, query, id)
+	tx, err := r.DB.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer rows.Close()
+	defer tx.Rollback()
 
-	var recipe Recipe
-	for rows.Next() {
-		var ingredient Ingredient
-		err = rows.Scan(&recipe.ID, &recipe.Title, &recipe.Instructions, &recipe.PrepTime, &recipe.CookTime, &recipe.Difficulty, &recipe.CuisineName, &ingredient.IngredientName, &ingredient.Quantity, &ingredient.Unit, &recipe.ImageLink)
-		if err != nil {
-			return nil, err
-		}
-		recipe.Ingredients = append(recipe.Ingredients, ingredient)
+	err = ensureHistory(ctx, tx, recipe.ID)
+	if err != nil {
+		return err
 	}
 
-	if err = rows.Err(); err != nil {
-		return nil, err
+	err = updateRecipe(ctx, tx, recipe, needsReview)
+	if err != nil {
+		return err
 	}
 
-	if len(recipe.Ingredients) == 0 {
-		return nil, ErrRecordNotFound
+	err = writeRevision(ctx, tx, recipe, author)
+	if err != nil {
+		return err
 	}
 
-	return &recipe, nil
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	r.suggestions.invalidate()
+
+	return nil
 }
 
-func (r RecipeModel) Update(recipe *Recipe) error {
+// updateRecipe updates a recipe along with its steps, tags and ingredients. When
+// needsReview is set, a published recipe is moved back to pending review, and a
+// recipe scheduled for publication loses its schedule.
+func updateRecipe(ctx context.Context, tx queryer, recipe *Recipe, needsReview bool) error {
+	cuisine, err := lookupCuisine(ctx, tx, recipe.CuisineName)
+	if err != nil {
+		return err
+	}
+
 	query := `
 	UPDATE recipes
-	SET recipename = $1, instructions = $2, preparationtime = $3, cookingtime = $4, difficultylevel = $5, cuisinename = (SELECT cuisinename FROM cuisine WHERE cuisinename = $6)
-	WHERE recipeid = $7`
+	SET recipename = $1, instructions = $2, preparationtime = $3, cookingtime = $4, difficultylevel = $5, cuisineid = $6, updated_at = NOW(),
+	    status = CASE WHEN $8::boolean AND status = 'published' THEN 'pending_review' ELSE status END,
+	    publish_at = CASE WHEN $8::boolean AND status IN ('published', 'pending_review') THEN NULL ELSE publish_at END
+	WHERE recipeid = $7 AND deleted_at IS NULL
+	RETURNING updated_at, status, review_reason, publish_at, author`
 
-	args := []interface{}{recipe.Title, recipe.Instructions, recipe.PrepTime, recipe.CookTime, recipe.Difficulty, recipe.CuisineName, recipe.ID}
+	args := []interface{}{recipe.Title, recipe.Instructions, recipe.PrepTime, recipe.CookTime, recipe.Difficulty, cuisine.ID, recipe.ID, needsReview}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	err := r.DB.QueryRowContext(ctx, query, args...).Scan()
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&recipe.UpdatedAt, &recipe.Status, &recipe.ReviewReason, &recipe.PublishAt, &recipe.Author)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -129,11 +432,30 @@ func (r RecipeModel) Update(recipe *Recipe) error {
 		}
 	}
 
+	err = replaceSteps(ctx, tx, recipe.ID, recipe.Steps)
+	if err != nil {
+		return err
+	}
+
+	err = replaceTags(ctx, tx, recipe.ID, recipe.Tags)
+	if err != nil {
+		return err
+	}
+
+	err = replaceIngredients(ctx, tx, recipe.ID, recipe.Ingredients)
+	if err != nil {
+		return err
+	}
+
+	recipe.CuisineName = cuisine.Name
+	recipe.TotalTime = recipe.PrepTime + recipe.CookTime
 	return nil
 }
 
+// Delete moves the recipe to the trash. It stays there, hidden from every other
+// RecipeModel method, until it is restored or purged.
 func (r RecipeModel) Delete(id int64) error {
-	query := `DELETE FROM recipes WHERE recipeid = $1`
+	query := `UPDATE recipes SET deleted_at = NOW() WHERE recipeid = $1 AND deleted_at IS NULL`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -146,11 +468,171 @@ func (r RecipeModel) Delete(id int64) error {
 	if rowsAffected == 0 {
 		return ErrRecordNotFound
 	}
+	r.suggestions.invalidate()
 
 	return nil
 }
 
-func (r RecipeModel) GetAll(title string, cuisineID int, filters Filters) ([]*Recipe, Metadata, error) {
+// Restore takes the recipe out of the trash, returning ErrRecordNotFound if it isn't
+// there or, when author is set, if it belongs to someone else. Restoring counts as a
+// change, so updated_at is advanced.
+func (r RecipeModel) Restore(id int64, author string) (*Recipe, error) {
+	query := `
+    UPDATE recipes SET deleted_at = NULL, updated_at = NOW()
+    WHERE recipeid = $1 AND deleted_at IS NOT NULL AND (author = $2 OR $2 = '')`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := r.DB.ExecContext(ctx, query, id, author)
+	if err != nil {
+		return nil, err
+	}
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return nil, ErrRecordNotFound
+	}
+	r.suggestions.invalidate()
+
+	return r.Get(id)
+}
+
+// TrashedRecipe is a recipe in the trash.
+type TrashedRecipe struct {
+	ID          int        `json:"id"`
+	Title       string     `json:"title"`
+	CuisineName string     `json:"cuisine_name"`
+	DeletedAt   time.Time  `json:"deleted_at"`
+	PurgeAt     *time.Time `json:"purge_at,omitempty"`
+}
+
+// GetTrash lists the recipes in the trash, or only those written by author when it is
+// set.
+func (r RecipeModel) GetTrash(author string, filters Filters) ([]*TrashedRecipe, Metadata, error) {
+	sortColumn := filters.sortColumn()
+	switch sortColumn {
+	case "id":
+		sortColumn = "r.recipeid"
+	case "title":
+		sortColumn = "r.recipename"
+	case "deleted_at":
+		sortColumn = "r.deleted_at"
+	}
+
+	query := fmt.Sprintf(`
+    SELECT COUNT(*) OVER(), r.recipeid, r.recipename, c.cuisinename, r.deleted_at
+    FROM recipes r
+    INNER JOIN cuisine c ON r.cuisineid = c.cuisineid
+    WHERE r.deleted_at IS NOT NULL AND (r.author = $1 OR $1 = '')
+    ORDER BY %s %s, r.recipeid ASC
+    LIMIT NULLIF($2, 0) OFFSET $3`, sortColumn, filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := r.DB.QueryContext(ctx, query, author, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	recipes := []*TrashedRecipe{}
+	for rows.Next() {
+		var recipe TrashedRecipe
+		err := rows.Scan(&totalRecords, &recipe.ID, &recipe.Title, &recipe.CuisineName, &recipe.DeletedAt)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		recipes = append(recipes, &recipe)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return recipes, metadata, nil
+}
+
+// Purge permanently deletes the recipes which have been in the trash for longer than
+// retention, along with their ingredients, steps, tags and image, and returns how many
+// there were.
+//
+// The dependent rows are deleted explicitly rather than left to ON DELETE CASCADE,
+// since databases whose tables predate the migrations may have foreign keys without
+// it. Forks of a purged recipe lose their parent.
+func (r RecipeModel) Purge(retention time.Duration) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	ids, err := expiredRecipes(ctx, tx, retention)
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	queries := []string{
+		`DELETE FROM recipeingredients WHERE recipeid = ANY($1::integer[])`,
+		`DELETE FROM recipe_images WHERE recipeid = ANY($1::integer[])`,
+		`DELETE FROM recipe_steps WHERE recipeid = ANY($1::integer[])`,
+		`DELETE FROM recipe_tags WHERE recipeid = ANY($1::integer[])`,
+		`DELETE FROM recipe_revisions WHERE recipeid = ANY($1::integer[])`,
+		`DELETE FROM recipe_similarities WHERE recipeid = ANY($1::integer[]) OR similarid = ANY($1::integer[])`,
+		`DELETE FROM recipe_favorites WHERE recipeid = ANY($1::integer[])`,
+		`DELETE FROM recipe_ratings WHERE recipeid = ANY($1::integer[])`,
+		`UPDATE recipes SET parent_id = NULL WHERE parent_id = ANY($1::integer[])`,
+	}
+	for _, query := range queries {
+		if _, err := tx.ExecContext(ctx, query, ids); err != nil {
+			return 0, err
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM recipes WHERE recipeid = ANY($1::integer[])`, ids)
+	if err != nil {
+		return 0, err
+	}
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return purged, tx.Commit()
+}
+
+// expiredRecipes locks and returns the recipes which have been in the trash for longer
+// than retention.
+func expiredRecipes(ctx context.Context, db queryer, retention time.Duration) ([]int, error) {
+	query := `
+        SELECT recipeid
+        FROM recipes
+        WHERE deleted_at < NOW() - make_interval(secs => $1)
+        FOR UPDATE`
+
+	rows, err := db.QueryContext(ctx, query, retention.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// GetAll lists the recipes matching criteria, whose Title matches any part of the recipe
+// title.
+func (r RecipeModel) GetAll(criteria RecipeCriteria, filters Filters) ([]*Recipe, Metadata, error) {
 
 	sortColumn := filters.sortColumn()
 	if sortColumn == "cuisinename" {
@@ -160,103 +642,83 @@ func (r RecipeModel) GetAll(title string, cuisineID int, filters Filters) ([]*Re
 	} else if sortColumn == "id" {
 		sortColumn = "r.recipeid"
 	} else if sortColumn == "difficulty" {
-		sortColumn = `CASE
-                        WHEN r.difficultylevel = 'Easy' THEN 1
-                        WHEN r.difficultylevel = 'Medium' THEN 2
-                        WHEN r.difficultylevel = 'Advanced' THEN 3
-                      END`
+		sortColumn = difficultyRank
+	} else if sortColumn == "total_time" {
+		sortColumn = totalTime
+	} else if sortColumn == "prep_time" {
+		sortColumn = "r.preparationtime"
+	} else if sortColumn == "cook_time" {
+		sortColumn = "r.cookingtime"
 	}
 
+	limits, limitArgs := criteria.RecipeLimits.conditions(10)
+
 	query := fmt.Sprintf(`
-    SELECT  r.recipeid, r.recipename, r.instructions, r.preparationtime, r.cookingtime, r.difficultylevel, c.cuisinename, i.ingredientname, ri.quantity, ri.unit, img.imageLink
+    SELECT COUNT(*) OVER(),`+recipeColumns+`
     FROM recipes r
     INNER JOIN cuisine c ON r.cuisineid = c.cuisineid
-    INNER JOIN recipeingredients ri ON r.recipeid = ri.recipeid
-    INNER JOIN ingredients i ON ri.ingredientid = i.ingredientid
-	INNER JOIN recipe_images img ON r.recipeid = img.recipeid
-    WHERE (LOWER(r.recipename) LIKE LOWER($1) OR $1 = '')
+    LEFT JOIN recipe_images img ON r.recipeid = img.recipeid
+    WHERE r.deleted_at IS NULL
+    AND (LOWER(r.recipename) LIKE LOWER($1) OR $1 = '')
     AND (r.cuisineid = $2 OR $2 = 0)
+    AND (c.slug = LOWER($3) OR LOWER(c.cuisinename) = LOWER($3) OR $3 = '')
+    AND (COALESCE(cardinality($4::text[]), 0) = 0 OR (
+        SELECT COUNT(*)
+        FROM recipe_tags rt
+        INNER JOIN tags t ON t.tagid = rt.tagid
+        WHERE rt.recipeid = r.recipeid AND t.name = ANY($4)
+    ) >= CASE WHEN $5 THEN 1 ELSE cardinality($4::text[]) END)
+    AND (r.status = $6 OR $6 = '')
+    AND (r.author = $7 OR $7 = '')
+    AND (r.parent_id = $8 OR $8 = 0)
+    AND NOT ($9 AND r.status = 'pending_review' AND r.publish_at IS NOT NULL)
+    AND %s
     ORDER BY %s %s, r.recipeid ASC`,
-	//LIMIT %d OFFSET %d`, 
-	sortColumn, filters.sortDirection())//, filters.limit(), filters.offset())
+		limits, sortColumn, filters.sortDirection())
+
+	args := append([]any{"%" + criteria.Title + "%", criteria.CuisineID, criteria.Cuisine, criteria.Tags, criteria.AnyTag, criteria.Status, criteria.Author, criteria.ParentID, criteria.ExcludeScheduled}, limitArgs...)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	rows, err := r.DB.QueryContext(ctx, query, "%"+title+"%", cuisineID)
+	rows, err := r.DB.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, Metadata{}, err
 	}
 	defer rows.Close()
 
-	recipes := make(map[int]*Recipe)
-	//totalRecords := 0
+	totalRecords := 0
+	recipes := []*Recipe{}
 
 	for rows.Next() {
-		var recipe Recipe
-		var ingredient Ingredient
-		err := rows.Scan(
-			// &totalRecords,
-			&recipe.ID,
-			&recipe.Title,
-			&recipe.Instructions,
-			&recipe.PrepTime,
-			&recipe.CookTime,
-			&recipe.Difficulty,
-			&recipe.CuisineName,
-			&ingredient.IngredientName,
-			&ingredient.Quantity,
-			&ingredient.Unit,
-			&recipe.ImageLink,
-		)
+		recipe, err := scanRecipe(rows, &totalRecords)
 		if err != nil {
 			return nil, Metadata{}, err
 		}
-
-		if _, exists := recipes[int(recipe.ID)]; !exists {
-			recipe.Ingredients = []Ingredient{ingredient}
-			recipes[int(recipe.ID)] = &recipe
-		} else {
-			recipes[int(recipe.ID)].Ingredients = append(recipes[int(recipe.ID)].Ingredients, ingredient)
-		}
+		recipes = append(recipes, recipe)
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, Metadata{}, err
 	}
 
-	result := []*Recipe{}
-	for _, recipe := range recipes {
-		result = append(result, recipe)
-	}
-
-	countQuery := `
-    SELECT COUNT(DISTINCT recipeid)
-    FROM recipes
-    WHERE (LOWER(recipename) LIKE LOWER($1) OR $1 = '')
-    AND (cuisineid = $2 OR $2 = 0)
-    `
-
-	var totalRecords int
-	err = r.DB.QueryRowContext(ctx, countQuery, "%"+title+"%", cuisineID).Scan(&totalRecords)
+	err = loadRecipeDetails(ctx, r.DB, recipes)
 	if err != nil {
 		return nil, Metadata{}, err
 	}
 
-	metadata := calculateMetadata(totalRecords, filters.Page)//, filters.PageSize)
-	return result, metadata, nil
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return recipes, metadata, nil
 }
 
-func (m *RecipeModel) Search(ingredients []string) ([]*Recipe, error) {
-	// Log the ingredients.
-	//log.Printf("Ingredients: %v\n", ingredients)
-
+// Search returns the published recipes which use every one of the given ingredients
+// and are within the given limits.
+func (m *RecipeModel) Search(ingredients []string, limits RecipeLimits) ([]*Recipe, error) {
 	//Return an error if the ingredients slice is empty.
 	if len(ingredients) == 0 {
 		return nil, errors.New("at least one ingredient must be provided")
 	}
 
-	// Generate a placeholder for each ingredient in the slice.
 	// Convert each ingredient to lowercase.
 	for i, ingredient := range ingredients {
 		ingredients[i] = strings.ToLower(ingredient)
@@ -271,31 +733,36 @@ func (m *RecipeModel) Search(ingredients []string) ([]*Recipe, error) {
 		placeholders += fmt.Sprintf("$%d", i+1)
 	}
 
+	conditions, limitArgs := limits.conditions(len(ingredients) + 1)
+
 	query := fmt.Sprintf(`
-    SELECT rv2.recipeid, rv2.recipename, rv2.instructions, rv2.preparationtime, rv2.cookingtime, rv2.difficultylevel, c.cuisinename, rv2.ingredientname, rv2.quantity, rv2.unit
-    FROM recipe_view rv2
-    INNER JOIN cuisine c ON rv2.cuisineid = c.cuisineid
-    INNER JOIN (
+    SELECT`+recipeColumns+`
+    FROM recipes r
+    INNER JOIN cuisine c ON r.cuisineid = c.cuisineid
+    LEFT JOIN recipe_images img ON r.recipeid = img.recipeid
+    WHERE r.deleted_at IS NULL AND r.status = 'published'
+    AND r.recipeid IN (
         SELECT recipeid
         FROM recipe_view rv1
         WHERE LOWER(ingredientname) IN (%s)
         GROUP BY recipeid
         HAVING COUNT(DISTINCT LOWER(ingredientname)) = %d
-    ) r ON rv2.recipeid = r.recipeid::bigint
-`, placeholders, len(ingredients))
-	// Log the generated query.
-	//log.Printf("Query: %s\n", query)
+    )
+    AND %s
+    ORDER BY r.recipeid
+`, placeholders, len(ingredients), conditions)
 
-	args := make([]interface{}, len(ingredients))
+	args := make([]interface{}, len(ingredients), len(ingredients)+len(limitArgs))
 	for i, ingredient := range ingredients {
 		args[i] = ingredient
 	}
+	args = append(args, limitArgs...)
 
-	// Log the arguments.
-//	log.Printf("Arguments: %v\n", args)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
 
 	// Pass the args slice to the DB.Query method.
-	rows, err := m.DB.Query(query, args...)
+	rows, err := m.DB.QueryContext(ctx, query, args...)
 	if err != nil {
 		// Log the error.
 		log.Printf("Error executing query: %v\n", err)
@@ -303,71 +770,73 @@ func (m *RecipeModel) Search(ingredients []string) ([]*Recipe, error) {
 	}
 	defer rows.Close()
 
-	recipes := make(map[int]*Recipe)
+	recipes := []*Recipe{}
 
 	for rows.Next() {
-		var recipe Recipe
-		var ingredient Ingredient
-
-		err := rows.Scan(
-			&recipe.ID,
-			&recipe.Title,
-			&recipe.Instructions,
-			&recipe.PrepTime,
-			&recipe.CookTime,
-			&recipe.Difficulty,
-			&recipe.CuisineName,
-			&ingredient.IngredientName,
-			&ingredient.Quantity,
-			&ingredient.Unit,
-		)
+		recipe, err := scanRecipe(rows)
 		if err != nil {
 			return nil, err
 		}
-
-		if existingRecipe, ok := recipes[recipe.ID]; ok {
-			existingRecipe.Ingredients = append(existingRecipe.Ingredients, ingredient)
-		} else {
-			recipe.Ingredients = []Ingredient{ingredient}
-			recipes[recipe.ID] = &recipe
-		}
+		recipes = append(recipes, recipe)
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, err
 	}
 
-	// Convert the map to a slice.
-	result := make([]*Recipe, 0, len(recipes))
-	for _, recipe := range recipes {
-		result = append(result, recipe)
+	err = loadRecipeDetails(ctx, m.DB, recipes)
+	if err != nil {
+		return nil, err
 	}
-	return result, nil
+	return recipes, nil
 }
 
-func (m *RecipeModel) ListAllIngredients() ([]string, error) {
-    query := `SELECT DISTINCT ingredientname FROM ingredients`
-
-    // Execute the query.
-    rows, err := m.DB.QueryContext(context.Background(), query)
-    if err != nil {
-        return nil, err
-    }
-    defer rows.Close()
-
-    ingredients := make([]string, 0)
-    for rows.Next() {
-        var ingredient string
-        if err := rows.Scan(&ingredient); err != nil {
-            return nil, err
-        }
-        ingredients = append(ingredients, ingredient)
-    }
-
-    // Check for errors from iterating over rows.
-    if err := rows.Err(); err != nil {
-        return nil, err
-    }
-
-    return ingredients, nil
-}
\ No newline at end of file
+// loadRecipeDetails fills in the ingredients and steps of the given recipes, using one
+// query per table rather than one per recipe.
+func loadRecipeDetails(ctx context.Context, db queryer, recipes []*Recipe) error {
+	if len(recipes) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(recipes))
+	byID := make(map[int]*Recipe, len(recipes))
+	for i, recipe := range recipes {
+		ids[i] = recipe.ID
+		byID[recipe.ID] = recipe
+		recipe.Ingredients = []Ingredient{}
+		recipe.Steps = []Step{}
+		recipe.Tags = []string{}
+	}
+
+	query := `
+    SELECT ri.recipeid, i.ingredientid, i.ingredientname, ri.quantity, ri.unit
+    FROM recipeingredients ri
+    INNER JOIN ingredients i ON ri.ingredientid = i.ingredientid
+    WHERE ri.recipeid = ANY($1)
+    ORDER BY ri.recipeid, i.ingredientname`
+
+	rows, err := db.QueryContext(ctx, query, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var recipeID int
+		var ingredient Ingredient
+		err := rows.Scan(&recipeID, &ingredient.IngredientID, &ingredient.IngredientName, &ingredient.Quantity, &ingredient.Unit)
+		if err != nil {
+			return err
+		}
+		byID[recipeID].Ingredients = append(byID[recipeID].Ingredients, ingredient)
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	err = loadSteps(ctx, db, ids, byID)
+	if err != nil {
+		return err
+	}
+	return loadTags(ctx, db, ids, byID)
+}
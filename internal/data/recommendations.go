@@ -0,0 +1,216 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"recipe.athif.com/internal/recommend"
+)
+
+// minLikedRating is the lowest rating which counts as liking a recipe. A rating of
+// minLikedRating weighs as much as a favorite, and each star above it half as much again.
+const minLikedRating = 4
+
+// RecommendedRecipe is a recipe recommended to an actor, with why it was chosen.
+type RecommendedRecipe struct {
+	Score       float64 `json:"score"`
+	Explanation string  `json:"explanation"`
+	Recipe      *Recipe `json:"recipe"`
+}
+
+// recommendColumns are the columns read by scanRecommendRecipe. Queries selecting them
+// must join cuisine as c.
+const recommendColumns = `
+    r.recipeid, r.cuisineid, c.cuisinename, r.difficultylevel, r.preparationtime + r.cookingtime,
+    COALESCE((
+        SELECT json_agg(json_build_object('id', i.ingredientid, 'name', i.ingredientname) ORDER BY i.ingredientid)
+        FROM recipeingredients ri
+        INNER JOIN ingredients i ON i.ingredientid = ri.ingredientid
+        WHERE ri.recipeid = r.recipeid
+    ), '[]')`
+
+func scanRecommendRecipe(row interface{ Scan(...any) error }, dest ...any) (recommend.Recipe, error) {
+	var recipe recommend.Recipe
+	var ingredients []byte
+	dest = append(dest,
+		&recipe.ID,
+		&recipe.CuisineID,
+		&recipe.Cuisine,
+		&recipe.Difficulty,
+		&recipe.TotalTime,
+		&ingredients,
+	)
+	if err := row.Scan(dest...); err != nil {
+		return recipe, err
+	}
+	err := json.Unmarshal(ingredients, &recipe.Ingredients)
+	return recipe, err
+}
+
+// Recommend returns up to n published recipes for the actor, ranked by a taste profile
+// built from the recipes the actor saved or rated highly. Recipes the actor has already
+// saved, rated or written are left out. An actor who hasn't liked anything yet gets no
+// recommendations.
+func (r RecipeModel) Recommend(actor string, n int) ([]*RecommendedRecipe, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	liked, err := r.liked(ctx, actor)
+	if err != nil {
+		return nil, err
+	}
+	profile := recommend.NewProfile(liked)
+	if profile.Empty() {
+		return []*RecommendedRecipe{}, nil
+	}
+
+	candidates, err := r.unseen(ctx, actor, liked)
+	if err != nil {
+		return nil, err
+	}
+	ranked := profile.Recommend(candidates, n)
+	if len(ranked) == 0 {
+		return []*RecommendedRecipe{}, nil
+	}
+
+	ids := make([]int, len(ranked))
+	for i, recommendation := range ranked {
+		ids[i] = recommendation.RecipeID
+	}
+
+	query := `
+    SELECT` + recipeColumns + `
+    FROM recipes r
+    INNER JOIN cuisine c ON r.cuisineid = c.cuisineid
+    LEFT JOIN recipe_images img ON r.recipeid = img.recipeid
+    WHERE r.recipeid = ANY($1::integer[]) AND r.deleted_at IS NULL AND r.status = 'published'`
+
+	rows, err := r.DB.QueryContext(ctx, query, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byID := make(map[int]*Recipe, len(ids))
+	recipes := []*Recipe{}
+	for rows.Next() {
+		recipe, err := scanRecipe(rows)
+		if err != nil {
+			return nil, err
+		}
+		byID[recipe.ID] = recipe
+		recipes = append(recipes, recipe)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = loadRecipeDetails(ctx, r.DB, recipes)
+	if err != nil {
+		return nil, err
+	}
+
+	recommended := make([]*RecommendedRecipe, 0, len(ranked))
+	for _, recommendation := range ranked {
+		recipe, ok := byID[recommendation.RecipeID]
+		if !ok {
+			// Trashed, unpublished or purged since the candidates were read.
+			continue
+		}
+		recommended = append(recommended, &RecommendedRecipe{
+			Score:       recommendation.Score,
+			Explanation: recommendation.Explanation,
+			Recipe:      recipe,
+		})
+	}
+	return recommended, nil
+}
+
+// liked returns the recipes the actor saved or rated at least minLikedRating, weighted
+// by how strongly they were liked.
+func (r RecipeModel) liked(ctx context.Context, actor string) ([]recommend.Liked, error) {
+	query := `
+    WITH liked AS (
+        SELECT recipeid, SUM(weight)::float8 AS weight
+        FROM (
+            SELECT recipeid, 1.0 AS weight FROM recipe_favorites WHERE actor = $1
+            UNION ALL
+            SELECT recipeid, 1.0 + (rating - $2) * 0.5 FROM recipe_ratings WHERE actor = $1 AND rating >= $2
+        ) w
+        GROUP BY recipeid
+    )
+    SELECT l.weight,` + recommendColumns + `
+    FROM liked l
+    INNER JOIN recipes r ON r.recipeid = l.recipeid
+    INNER JOIN cuisine c ON r.cuisineid = c.cuisineid
+    WHERE r.deleted_at IS NULL`
+
+	rows, err := r.DB.QueryContext(ctx, query, actor, minLikedRating)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	liked := []recommend.Liked{}
+	for rows.Next() {
+		var weight float64
+		recipe, err := scanRecommendRecipe(rows, &weight)
+		if err != nil {
+			return nil, err
+		}
+		liked = append(liked, recommend.Liked{Recipe: recipe, Weight: weight})
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return liked, nil
+}
+
+// unseen returns the published recipes the actor hasn't saved, rated or written which
+// share a cuisine or an ingredient with a liked recipe. The profile would score any
+// other recipe as no match at all, so there's no need to read it.
+func (r RecipeModel) unseen(ctx context.Context, actor string, liked []recommend.Liked) ([]recommend.Recipe, error) {
+	cuisines := []int{}
+	ingredients := []int{}
+	for _, recipe := range liked {
+		cuisines = append(cuisines, recipe.CuisineID)
+		for _, ingredient := range recipe.Ingredients {
+			ingredients = append(ingredients, ingredient.ID)
+		}
+	}
+
+	query := `
+    WITH related AS (
+        SELECT recipeid FROM recipes WHERE cuisineid = ANY($2::integer[])
+        AND deleted_at IS NULL AND status = 'published'
+        UNION
+        SELECT recipeid FROM recipeingredients WHERE ingredientid = ANY($3::integer[])
+    )
+    SELECT` + recommendColumns + `
+    FROM related
+    INNER JOIN recipes r ON r.recipeid = related.recipeid
+    INNER JOIN cuisine c ON r.cuisineid = c.cuisineid
+    WHERE r.deleted_at IS NULL AND r.status = 'published' AND r.author <> $1
+    AND NOT EXISTS (SELECT 1 FROM recipe_favorites f WHERE f.actor = $1 AND f.recipeid = r.recipeid)
+    AND NOT EXISTS (SELECT 1 FROM recipe_ratings rr WHERE rr.actor = $1 AND rr.recipeid = r.recipeid)`
+
+	rows, err := r.DB.QueryContext(ctx, query, actor, cuisines, ingredients)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	candidates := []recommend.Recipe{}
+	for rows.Next() {
+		recipe, err := scanRecommendRecipe(rows)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, recipe)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return candidates, nil
+}
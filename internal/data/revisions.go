@@ -0,0 +1,351 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// RecipeSnapshot is the content of a recipe, without its ID or timestamps. It is what
+// each revision records, and the body of a backup record.
+type RecipeSnapshot struct {
+	Title        string                   `json:"title"`
+	Instructions string                   `json:"instructions"`
+	Steps        []Step                   `json:"steps"`
+	PrepTime     Mins                     `json:"prep_time"`
+	CookTime     Mins                     `json:"cook_time"`
+	Difficulty   Difficulty               `json:"difficulty"`
+	CuisineName  string                   `json:"cuisine_name"`
+	Ingredients  []BackupRecipeIngredient `json:"ingredients"`
+	Tags         []string                 `json:"tags"`
+	ImageLink    string                   `json:"image_link"`
+	// Status is recorded so that the history shows when a recipe was published, but
+	// rolling back to a revision leaves the recipe's status as it is. Revisions from
+	// before the publishing workflow have none.
+	Status Status `json:"status,omitempty"`
+}
+
+func snapshotOf(recipe *Recipe) RecipeSnapshot {
+	snapshot := RecipeSnapshot{
+		Title:        recipe.Title,
+		Instructions: recipe.Instructions,
+		Steps:        recipe.Steps,
+		PrepTime:     recipe.PrepTime,
+		CookTime:     recipe.CookTime,
+		Difficulty:   recipe.Difficulty,
+		CuisineName:  recipe.CuisineName,
+		Ingredients:  make([]BackupRecipeIngredient, len(recipe.Ingredients)),
+		Tags:         recipe.Tags,
+		ImageLink:    recipe.ImageLink,
+		Status:       recipe.Status,
+	}
+	for i, ingredient := range recipe.Ingredients {
+		snapshot.Ingredients[i] = BackupRecipeIngredient{Name: ingredient.IngredientName, Quantity: ingredient.Quantity, Unit: ingredient.Unit}
+	}
+	if snapshot.Steps == nil {
+		snapshot.Steps = []Step{}
+	}
+	if snapshot.Tags == nil {
+		snapshot.Tags = []string{}
+	}
+	return snapshot
+}
+
+// recipe converts the snapshot back to a Recipe.
+func (s *RecipeSnapshot) recipe() *Recipe {
+	recipe := &Recipe{
+		Title:        s.Title,
+		Instructions: s.Instructions,
+		Steps:        s.Steps,
+		PrepTime:     s.PrepTime,
+		CookTime:     s.CookTime,
+		Difficulty:   s.Difficulty,
+		CuisineName:  s.CuisineName,
+		Ingredients:  make([]Ingredient, len(s.Ingredients)),
+		Tags:         NormalizeTags(s.Tags),
+		ImageLink:    s.ImageLink,
+	}
+	for i, ingredient := range s.Ingredients {
+		recipe.Ingredients[i] = Ingredient{IngredientName: ingredient.Name, Quantity: ingredient.Quantity, Unit: ingredient.Unit}
+	}
+	recipe.SyncInstructions()
+	return recipe
+}
+
+// Revision is a saved version of a recipe. Revision 1 is the recipe as it was created,
+// or as it was when history began for recipes that are older than it. Recipe is only
+// filled in when a single revision is fetched.
+type Revision struct {
+	Revision  int             `json:"revision"`
+	Author    string          `json:"author"`
+	CreatedAt time.Time       `json:"created_at"`
+	Recipe    *RecipeSnapshot `json:"recipe,omitempty"`
+}
+
+// Change is one difference between two snapshots. Field names a top-level field, or
+// an element of a list: "steps[2]" for the step at position 2, or
+// "ingredients[flour]" for an ingredient. Old or New is nil when an element was added
+// or removed.
+type Change struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+// DiffSnapshots lists the changes that turn a into b, in field order.
+func DiffSnapshots(a, b *RecipeSnapshot) []Change {
+	changes := []Change{}
+	add := func(field string, old, new any) {
+		if !reflect.DeepEqual(old, new) {
+			changes = append(changes, Change{Field: field, Old: old, New: new})
+		}
+	}
+
+	add("title", a.Title, b.Title)
+	add("instructions", a.Instructions, b.Instructions)
+
+	steps := map[int][2]*Step{}
+	var positions []int
+	for i, list := range [][]Step{a.Steps, b.Steps} {
+		for j := range list {
+			step := &list[j]
+			pair, seen := steps[step.Position]
+			if !seen {
+				positions = append(positions, step.Position)
+			}
+			pair[i] = step
+			steps[step.Position] = pair
+		}
+	}
+	sort.Ints(positions)
+	for _, position := range positions {
+		pair := steps[position]
+		add("steps["+strconv.Itoa(position)+"]", nilIfNone(pair[0]), nilIfNone(pair[1]))
+	}
+
+	add("prep_time", a.PrepTime, b.PrepTime)
+	add("cook_time", a.CookTime, b.CookTime)
+	add("difficulty", a.Difficulty, b.Difficulty)
+	add("cuisine_name", a.CuisineName, b.CuisineName)
+
+	ingredients := map[string][2]*BackupRecipeIngredient{}
+	var names []string
+	for i, list := range [][]BackupRecipeIngredient{a.Ingredients, b.Ingredients} {
+		for j := range list {
+			ingredient := &list[j]
+			pair, seen := ingredients[ingredient.Name]
+			if !seen {
+				names = append(names, ingredient.Name)
+			}
+			pair[i] = ingredient
+			ingredients[ingredient.Name] = pair
+		}
+	}
+	for _, name := range names {
+		pair := ingredients[name]
+		add("ingredients["+name+"]", nilIfNone(pair[0]), nilIfNone(pair[1]))
+	}
+
+	add("tags", a.Tags, b.Tags)
+	add("image_link", a.ImageLink, b.ImageLink)
+	return changes
+}
+
+// DiffRecipes lists the changes that turn recipe a into recipe b, as DiffSnapshots
+// does for revisions.
+func DiffRecipes(a, b *Recipe) []Change {
+	snapshotA, snapshotB := snapshotOf(a), snapshotOf(b)
+	return DiffSnapshots(&snapshotA, &snapshotB)
+}
+
+// nilIfNone turns a nil pointer into an untyped nil, so that DeepEqual treats a missing
+// element on both sides as equal and it encodes as null.
+func nilIfNone[T any](p *T) any {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
+
+// writeRevision records the recipe as its next revision.
+func writeRevision(ctx context.Context, db queryer, recipe *Recipe, author string) error {
+	snapshot, err := json.Marshal(snapshotOf(recipe))
+	if err != nil {
+		return err
+	}
+
+	query := `
+        INSERT INTO recipe_revisions (recipeid, revision, snapshot, author)
+        SELECT $1, COALESCE(MAX(revision), 0) + 1, $2, $3
+        FROM recipe_revisions
+        WHERE recipeid = $1`
+
+	_, err = db.ExecContext(ctx, query, recipe.ID, snapshot, author)
+	return err
+}
+
+// ensureHistory records the recipe as it currently is when it has no revisions yet,
+// which is the case for recipes created before revisions were kept. The author of
+// that first revision is unknown.
+//
+// It first locks the recipe's row until the transaction ends, so that transactions
+// writing revisions of the same recipe take turns. Otherwise two of them could both
+// find no history, or pick the same next revision number, and one would fail on the
+// primary key.
+func ensureHistory(ctx context.Context, db queryer, id int) error {
+	var exists bool
+	query := `
+        SELECT EXISTS (SELECT 1 FROM recipe_revisions WHERE recipeid = $1)
+        FROM recipes
+        WHERE recipeid = $1
+        FOR UPDATE`
+
+	err := db.QueryRowContext(ctx, query, id).Scan(&exists)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+	if exists {
+		return nil
+	}
+
+	recipe, err := getRecipe(ctx, db, int64(id))
+	if err != nil {
+		return err
+	}
+	return writeRevision(ctx, db, recipe, "")
+}
+
+// GetRevisions lists a recipe's revisions, newest first, without their snapshots.
+func (r RecipeModel) GetRevisions(id int64, filters Filters) ([]*Revision, Metadata, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var exists bool
+	err := r.DB.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM recipes WHERE recipeid = $1 AND deleted_at IS NULL)`, id).Scan(&exists)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	if !exists {
+		return nil, Metadata{}, ErrRecordNotFound
+	}
+
+	query := fmt.Sprintf(`
+        SELECT COUNT(*) OVER(), revision, author, created_at
+        FROM recipe_revisions
+        WHERE recipeid = $1
+        ORDER BY revision %s
+        LIMIT NULLIF($2, 0) OFFSET $3`, filters.sortDirection())
+
+	rows, err := r.DB.QueryContext(ctx, query, id, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	revisions := []*Revision{}
+	for rows.Next() {
+		var revision Revision
+		err := rows.Scan(&totalRecords, &revision.Revision, &revision.Author, &revision.CreatedAt)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		revisions = append(revisions, &revision)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return revisions, metadata, nil
+}
+
+// GetRevision returns a single revision with its snapshot.
+func (r RecipeModel) GetRevision(id int64, number int) (*Revision, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return getRevision(ctx, r.DB, id, number)
+}
+
+func getRevision(ctx context.Context, db queryer, id int64, number int) (*Revision, error) {
+	query := `
+        SELECT rv.revision, rv.author, rv.created_at, rv.snapshot
+        FROM recipe_revisions rv
+        INNER JOIN recipes r ON r.recipeid = rv.recipeid
+        WHERE rv.recipeid = $1 AND rv.revision = $2 AND r.deleted_at IS NULL`
+
+	var revision Revision
+	var snapshot []byte
+	err := db.QueryRowContext(ctx, query, id, number).Scan(&revision.Revision, &revision.Author, &revision.CreatedAt, &snapshot)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	revision.Recipe = &RecipeSnapshot{}
+	err = json.Unmarshal(snapshot, revision.Recipe)
+	if err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// RestoreRevision rolls the recipe back to the given revision. The rollback is itself
+// saved as a new revision, so it can be undone in turn. needsReview is as for Update.
+func (r RecipeModel) RestoreRevision(id int64, number int, author string, needsReview bool) (*Recipe, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = ensureHistory(ctx, tx, int(id))
+	if err != nil {
+		return nil, err
+	}
+	revision, err := getRevision(ctx, tx, id, number)
+	if err != nil {
+		return nil, err
+	}
+
+	recipe := revision.Recipe.recipe()
+	recipe.ID = int(id)
+	err = updateRecipe(ctx, tx, recipe, needsReview)
+	if err != nil {
+		return nil, err
+	}
+	err = replaceImage(ctx, tx, recipe.ID, recipe.ImageLink)
+	if err != nil {
+		return nil, err
+	}
+	err = writeRevision(ctx, tx, recipe, author)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	r.suggestions.invalidate()
+	return recipe, nil
+}
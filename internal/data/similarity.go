@@ -0,0 +1,209 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"recipe.athif.com/internal/similarity"
+)
+
+// SimilarRecipe is a recipe along with how similar it is to the one it was found for.
+type SimilarRecipe struct {
+	Score  float64 `json:"score"`
+	Recipe *Recipe `json:"recipe"`
+}
+
+// SimilarityModel reads the recipe features similarity is computed from and stores the
+// scores, which are kept in recipe_similarities between runs.
+type SimilarityModel struct {
+	DB *sql.DB
+}
+
+// Features returns the cuisine, difficulty and ingredients of every published recipe
+// which isn't in the trash.
+func (m SimilarityModel) Features(ctx context.Context) ([]similarity.Recipe, error) {
+	query := `
+        SELECT r.recipeid, r.cuisineid, r.difficultylevel,
+            COALESCE(json_agg(ri.ingredientid) FILTER (WHERE ri.ingredientid IS NOT NULL), '[]')
+        FROM recipes r
+        LEFT JOIN recipeingredients ri ON ri.recipeid = r.recipeid
+        WHERE r.deleted_at IS NULL AND r.status = 'published'
+        GROUP BY r.recipeid`
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recipes := []similarity.Recipe{}
+	for rows.Next() {
+		var recipe similarity.Recipe
+		var ingredients []byte
+		err := rows.Scan(&recipe.ID, &recipe.CuisineID, &recipe.Difficulty, &ingredients)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(ingredients, &recipe.Ingredients); err != nil {
+			return nil, err
+		}
+		recipes = append(recipes, recipe)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return recipes, nil
+}
+
+// ChangedSince returns the recipes which were edited, restored, trashed or moved through
+// the publishing workflow after since, along with the database's current time to pass
+// as since on the next call.
+func (m SimilarityModel) ChangedSince(ctx context.Context, since time.Time) ([]int, time.Time, error) {
+	var now time.Time
+	err := m.DB.QueryRowContext(ctx, `SELECT NOW()`).Scan(&now)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	query := `
+        SELECT recipeid
+        FROM recipes
+        WHERE GREATEST(updated_at, deleted_at) > $1`
+
+	rows, err := m.DB.QueryContext(ctx, query, since)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, time.Time{}, err
+		}
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, time.Time{}, err
+	}
+	return ids, now, nil
+}
+
+// Replace drops every stored score involving the recipes with the given IDs and saves
+// pairs in their place.
+func (m SimilarityModel) Replace(ctx context.Context, ids []int, pairs []similarity.Pair) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+        DELETE FROM recipe_similarities
+        WHERE recipeid = ANY($1::integer[]) OR similarid = ANY($1::integer[])`
+
+	_, err = tx.ExecContext(ctx, query, ids)
+	if err != nil {
+		return err
+	}
+	if err = insertSimilarities(ctx, tx, pairs); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ReplaceAll swaps every stored score for pairs.
+func (m SimilarityModel) ReplaceAll(ctx context.Context, pairs []similarity.Pair) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM recipe_similarities`)
+	if err != nil {
+		return err
+	}
+	if err = insertSimilarities(ctx, tx, pairs); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// insertSimilarities saves pairs in batches, skipping any recipe which has been purged
+// since its features were read.
+func insertSimilarities(ctx context.Context, db queryer, pairs []similarity.Pair) error {
+	const batchSize = 5000
+
+	query := `
+        INSERT INTO recipe_similarities (recipeid, similarid, score)
+        SELECT p.recipeid, p.similarid, p.score
+        FROM unnest($1::integer[], $2::integer[], $3::real[]) AS p (recipeid, similarid, score)
+        INNER JOIN recipes a ON a.recipeid = p.recipeid
+        INNER JOIN recipes b ON b.recipeid = p.similarid
+        ON CONFLICT (recipeid, similarid) DO UPDATE SET score = EXCLUDED.score`
+
+	for start := 0; start < len(pairs); start += batchSize {
+		batch := pairs[start:min(start+batchSize, len(pairs))]
+		recipeIDs := make([]int, len(batch))
+		similarIDs := make([]int, len(batch))
+		scores := make([]float64, len(batch))
+		for i, pair := range batch {
+			recipeIDs[i] = pair.RecipeID
+			similarIDs[i] = pair.SimilarID
+			scores[i] = pair.Score
+		}
+		_, err := db.ExecContext(ctx, query, recipeIDs, similarIDs, scores)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetSimilar returns up to n of the published recipes most similar to the recipe with
+// the given ID, best first.
+func (r RecipeModel) GetSimilar(id int64, n int) ([]*SimilarRecipe, error) {
+	query := `
+    SELECT s.score,` + recipeColumns + `
+    FROM recipe_similarities s
+    INNER JOIN recipes r ON r.recipeid = s.similarid
+    INNER JOIN cuisine c ON r.cuisineid = c.cuisineid
+    LEFT JOIN recipe_images img ON r.recipeid = img.recipeid
+    WHERE s.recipeid = $1 AND r.deleted_at IS NULL AND r.status = 'published'
+    ORDER BY s.score DESC, r.recipeid
+    LIMIT $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := r.DB.QueryContext(ctx, query, id, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	similar := []*SimilarRecipe{}
+	recipes := []*Recipe{}
+	for rows.Next() {
+		var score float64
+		recipe, err := scanRecipe(rows, &score)
+		if err != nil {
+			return nil, err
+		}
+		similar = append(similar, &SimilarRecipe{Score: score, Recipe: recipe})
+		recipes = append(recipes, recipe)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = loadRecipeDetails(ctx, r.DB, recipes)
+	if err != nil {
+		return nil, err
+	}
+	return similar, nil
+}
@@ -0,0 +1,206 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// Status is where a recipe is in the publishing workflow. Only published recipes are
+// public; the others are visible to their author and to moderators.
+type Status string
+
+const (
+	StatusDraft         Status = "draft"
+	StatusPendingReview Status = "pending_review"
+	StatusPublished     Status = "published"
+	StatusRejected      Status = "rejected"
+)
+
+// Statuses lists every status in workflow order.
+var Statuses = []Status{StatusDraft, StatusPendingReview, StatusPublished, StatusRejected}
+
+// statusTransitions lists the statuses each status may move to. Authors submit drafts
+// for review and may withdraw a recipe to a draft at any point, while moderators
+// approve or reject what is pending.
+var statusTransitions = map[Status][]Status{
+	StatusDraft:         {StatusPendingReview},
+	StatusPendingReview: {StatusPublished, StatusRejected, StatusDraft},
+	StatusPublished:     {StatusDraft},
+	StatusRejected:      {StatusPendingReview, StatusDraft},
+}
+
+// CanBecome reports whether a recipe with status s may be moved to status to.
+func (s Status) CanBecome(to Status) bool {
+	return slices.Contains(statusTransitions[s], to)
+}
+
+func (s Status) Value() (driver.Value, error) {
+	return string(s), nil
+}
+
+// SetStatus moves the recipe to status to, returning ErrInvalidTransition if its
+// current status doesn't allow it. reason is kept only for rejections. Publishing
+// with publishAt in the future leaves the recipe pending review until PublishDue
+// publishes it; a zero publishAt publishes it at once. Such a scheduled recipe may
+// only be withdrawn to a draft. The change is recorded as a revision by author.
+func (r RecipeModel) SetStatus(id int64, to Status, reason string, publishAt time.Time, author string) (*Recipe, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var from Status
+	var scheduled bool
+	query := `
+        SELECT status, publish_at IS NOT NULL
+        FROM recipes
+        WHERE recipeid = $1 AND deleted_at IS NULL
+        FOR UPDATE`
+	err = tx.QueryRowContext(ctx, query, id).Scan(&from, &scheduled)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	if !from.CanBecome(to) {
+		return nil, fmt.Errorf("%w from %s to %s", ErrInvalidTransition, from, to)
+	}
+	// A recipe approved for a later publication has been reviewed, so it can only go
+	// back to a draft until PublishDue publishes it.
+	if from == StatusPendingReview && scheduled && to != StatusDraft {
+		return nil, fmt.Errorf("%w from %s to %s: the recipe is scheduled for publication", ErrInvalidTransition, from, to)
+	}
+	err = ensureHistory(ctx, tx, int(id))
+	if err != nil {
+		return nil, err
+	}
+
+	var schedule sql.NullTime
+	if to == StatusPublished {
+		schedule = sql.NullTime{Time: time.Now(), Valid: true}
+		if publishAt.After(schedule.Time) {
+			to = StatusPendingReview
+			schedule.Time = publishAt
+		}
+	}
+	if to != StatusRejected {
+		reason = ""
+	}
+
+	query = `
+        UPDATE recipes
+        SET status = $2, review_reason = $3, publish_at = $4, updated_at = NOW()
+        WHERE recipeid = $1`
+
+	_, err = tx.ExecContext(ctx, query, id, to, reason, schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	recipe, err := getRecipe(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	err = writeRevision(ctx, tx, recipe, author)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	r.suggestions.invalidate()
+	return recipe, nil
+}
+
+// PublishDue publishes the approved recipes whose scheduled time has come, recording
+// each as a revision by author, and returns their IDs.
+func (r RecipeModel) PublishDue(author string) ([]int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ids, err := dueForPublication(ctx, tx)
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	query := `
+        UPDATE recipes
+        SET status = 'published', updated_at = NOW()
+        WHERE recipeid = ANY($1::integer[])`
+
+	for _, id := range ids {
+		err = ensureHistory(ctx, tx, int(id))
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err = tx.ExecContext(ctx, query, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		recipe, err := getRecipe(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+		err = writeRevision(ctx, tx, recipe, author)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	r.suggestions.invalidate()
+	return ids, nil
+}
+
+// dueForPublication locks and returns the approved recipes whose scheduled time has
+// come.
+func dueForPublication(ctx context.Context, tx *sql.Tx) ([]int64, error) {
+	query := `
+        SELECT recipeid
+        FROM recipes
+        WHERE status = 'pending_review' AND publish_at <= NOW() AND deleted_at IS NULL
+        ORDER BY recipeid
+        FOR UPDATE`
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
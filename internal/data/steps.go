@@ -0,0 +1,188 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"recipe.athif.com/internal/validator"
+)
+
+// Step is one numbered step of a recipe's method. Duration, Ingredients and ImageLink
+// are optional; Ingredients holds the names of the recipe ingredients the step uses.
+type Step struct {
+	Position    int      `json:"position"`
+	Text        string   `json:"text"`
+	Duration    Mins     `json:"duration,omitempty"`
+	Ingredients []string `json:"ingredients,omitempty"`
+	ImageLink   string   `json:"image_link,omitempty"`
+	// durationFormat is copied from the recipe when it is encoded.
+	durationFormat DurationFormat
+}
+
+func (s Step) MarshalJSON() ([]byte, error) {
+	var duration json.RawMessage
+	if s.Duration != 0 {
+		js, err := s.durationFormat.marshal(s.Duration)
+		if err != nil {
+			return nil, err
+		}
+		duration = js
+	}
+
+	return json.Marshal(struct {
+		Position    int             `json:"position"`
+		Text        string          `json:"text"`
+		Duration    json.RawMessage `json:"duration,omitempty"`
+		Ingredients []string        `json:"ingredients,omitempty"`
+		ImageLink   string          `json:"image_link,omitempty"`
+	}{s.Position, s.Text, duration, s.Ingredients, s.ImageLink})
+}
+
+// stepNumberRX matches the "1.", "2)" or "3 -" numbering at the start of an
+// instructions line.
+var stepNumberRX = regexp.MustCompile(`^\s*\d+\s*[.):-]\s*`)
+
+// SplitInstructions breaks an instructions blob into steps. A line starting with a
+// number begins a new step and any other line is folded into the current one, so text
+// without numbering becomes a step per line. The same rules are used by the migration
+// that created the recipe_steps table.
+func SplitInstructions(instructions string) []Step {
+	steps := []Step{}
+	numbered := false
+	for _, line := range strings.Split(instructions, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if stepNumberRX.MatchString(line) {
+			numbered = true
+			line = stepNumberRX.ReplaceAllString(line, "")
+			if line == "" {
+				continue
+			}
+		} else if numbered && len(steps) > 0 {
+			steps[len(steps)-1].Text += "\n" + line
+			continue
+		}
+		steps = append(steps, Step{Position: len(steps) + 1, Text: line})
+	}
+	return steps
+}
+
+// JoinSteps renders steps as a numbered instructions blob, for clients that still read
+// the instructions field.
+func JoinSteps(steps []Step) string {
+	lines := make([]string, len(steps))
+	for i, step := range steps {
+		lines[i] = fmt.Sprintf("%d. %s", i+1, step.Text)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// SyncInstructions fills in whichever of Steps and Instructions is missing from the
+// other. When both are given the steps win and the instructions are regenerated, so
+// the two never disagree. Positions are renumbered from one.
+func (r *Recipe) SyncInstructions() {
+	switch {
+	case len(r.Steps) > 0:
+		r.Instructions = JoinSteps(r.Steps)
+	case r.Instructions != "":
+		r.Steps = SplitInstructions(r.Instructions)
+	}
+	for i := range r.Steps {
+		r.Steps[i].Position = i + 1
+	}
+}
+
+// UpdateInstructions applies an update's instructions and steps to a stored recipe.
+// Steps which are given replace the stored ones. Without steps, the stored steps are
+// kept unless the instructions changed, in which case they're rebuilt from the new
+// instructions; this way a client which only knows about instructions doesn't lose
+// the steps' durations and ingredients by sending the recipe back unchanged.
+func (r *Recipe) UpdateInstructions(instructions string, steps []Step) {
+	switch {
+	case steps != nil:
+		r.Steps = steps
+	case instructions != r.Instructions:
+		r.Steps = nil
+	}
+	r.Instructions = instructions
+	r.SyncInstructions()
+}
+
+func ValidateSteps(v *validator.Validator, steps []Step) {
+	v.Check(len(steps) <= 100, "steps", "must not contain more than 100 steps")
+	for _, step := range steps {
+		if strings.TrimSpace(step.Text) == "" {
+			v.AddError("steps", "must not contain a step without text")
+		}
+		if step.Duration < 0 {
+			v.AddError("steps", "must not contain a negative duration")
+		}
+		if len(step.Text) > 5000 {
+			v.AddError("steps", "must not contain a step more than 5000 bytes long")
+		}
+	}
+}
+
+// replaceSteps overwrites the steps stored for a recipe.
+func replaceSteps(ctx context.Context, db queryer, recipeID int, steps []Step) error {
+	_, err := db.ExecContext(ctx, `DELETE FROM recipe_steps WHERE recipeid = $1`, recipeID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+        INSERT INTO recipe_steps (recipeid, position, body, duration, ingredients, imagelink)
+        VALUES ($1, $2, $3, NULLIF($4, 0), $5, $6)`
+
+	for _, step := range steps {
+		ingredients := step.Ingredients
+		if ingredients == nil {
+			ingredients = []string{}
+		}
+		js, err := json.Marshal(ingredients)
+		if err != nil {
+			return err
+		}
+		_, err = db.ExecContext(ctx, query, recipeID, step.Position, step.Text, int32(step.Duration), string(js), step.ImageLink)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadSteps fills in the steps of the recipes in byID.
+func loadSteps(ctx context.Context, db queryer, ids []int, byID map[int]*Recipe) error {
+	query := `
+    SELECT recipeid, position, body, COALESCE(duration, 0), ingredients, imagelink
+    FROM recipe_steps
+    WHERE recipeid = ANY($1)
+    ORDER BY recipeid, position`
+
+	rows, err := db.QueryContext(ctx, query, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var recipeID int
+		var step Step
+		var ingredients []byte
+		err := rows.Scan(&recipeID, &step.Position, &step.Text, &step.Duration, &ingredients, &step.ImageLink)
+		if err != nil {
+			return err
+		}
+		err = json.Unmarshal(ingredients, &step.Ingredients)
+		if err != nil {
+			return err
+		}
+		byID[recipeID].Steps = append(byID[recipeID].Steps, step)
+	}
+	return rows.Err()
+}
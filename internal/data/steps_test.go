@@ -0,0 +1,57 @@
+package data
+
+import (
+	"reflect"
+	"testing"
+)
+
+func storedRecipe() *Recipe {
+	recipe := &Recipe{
+		Steps: []Step{
+			{Position: 1, Text: "Chop the onions.", Duration: 5, Ingredients: []string{"onion"}},
+			{Position: 2, Text: "Simmer.", Duration: 30},
+		},
+	}
+	recipe.SyncInstructions()
+	return recipe
+}
+
+func TestUpdateInstructions(t *testing.T) {
+	stored := storedRecipe()
+
+	tests := []struct {
+		name         string
+		instructions string
+		steps        []Step
+		want         []Step
+	}{
+		{
+			name:         "steps omitted, instructions unchanged",
+			instructions: stored.Instructions,
+			want:         stored.Steps,
+		},
+		{
+			name:         "steps omitted, instructions changed",
+			instructions: "1. Chop the leeks.\n2. Simmer.",
+			want:         []Step{{Position: 1, Text: "Chop the leeks."}, {Position: 2, Text: "Simmer."}},
+		},
+		{
+			name:         "steps given",
+			instructions: "ignored",
+			steps:        []Step{{Text: "Boil.", Duration: 10}},
+			want:         []Step{{Position: 1, Text: "Boil.", Duration: 10}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recipe := storedRecipe()
+			recipe.UpdateInstructions(tt.instructions, tt.steps)
+			if !reflect.DeepEqual(recipe.Steps, tt.want) {
+				t.Errorf("Steps = %+v, want %+v", recipe.Steps, tt.want)
+			}
+			if want := JoinSteps(tt.want); recipe.Instructions != want {
+				t.Errorf("Instructions = %q, want %q", recipe.Instructions, want)
+			}
+		})
+	}
+}
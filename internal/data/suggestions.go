@@ -0,0 +1,134 @@
+package data
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// suggestionTTL caps how long a cached suggestion list is served even if nothing
+// invalidates it, e.g. when the database is changed by hand.
+const suggestionTTL = 10 * time.Minute
+
+// maxCachedPrefixes bounds the memory used by the cache. When it is full the whole
+// cache is dropped, which is cheap to rebuild for short prefixes.
+const maxCachedPrefixes = 10_000
+
+type IngredientSuggestion struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	RecipeCount int    `json:"recipe_count"`
+}
+
+// suggestionCache is an in-process cache of autocomplete results keyed by prefix and
+// limit. Anything that changes ingredient names, aliases or how many recipes use an
+// ingredient must call invalidate.
+type suggestionCache struct {
+	mu      sync.RWMutex
+	entries map[suggestionKey]suggestionEntry
+}
+
+type suggestionKey struct {
+	prefix string
+	limit  int
+}
+
+type suggestionEntry struct {
+	suggestions []IngredientSuggestion
+	expires     time.Time
+}
+
+func newSuggestionCache() *suggestionCache {
+	return &suggestionCache{entries: make(map[suggestionKey]suggestionEntry)}
+}
+
+func (c *suggestionCache) get(key suggestionKey) ([]IngredientSuggestion, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.suggestions, true
+}
+
+func (c *suggestionCache) set(key suggestionKey, suggestions []IngredientSuggestion) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= maxCachedPrefixes {
+		c.entries = make(map[suggestionKey]suggestionEntry)
+	}
+	c.entries[key] = suggestionEntry{suggestions: suggestions, expires: time.Now().Add(suggestionTTL)}
+}
+
+func (c *suggestionCache) invalidate() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[suggestionKey]suggestionEntry)
+}
+
+// Suggest returns up to limit ingredients whose name or an alias starts with prefix.
+// Exact name matches come first, then other name matches, then alias matches; within
+// each group the ingredients used by the most recipes are listed first.
+func (m IngredientModel) Suggest(prefix string, limit int) ([]IngredientSuggestion, error) {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	key := suggestionKey{prefix: prefix, limit: limit}
+
+	if suggestions, ok := m.suggestions.get(key); ok {
+		return suggestions, nil
+	}
+
+	query := `
+        WITH matches AS (
+            SELECT ingredientid, CASE WHEN LOWER(ingredientname) = $1 THEN 0 ELSE 1 END AS rank
+            FROM ingredients
+            WHERE LOWER(ingredientname) LIKE $2 || '%'
+            UNION ALL
+            SELECT ingredientid, 2
+            FROM ingredient_aliases
+            WHERE alias LIKE $2 || '%'
+        )
+        SELECT i.ingredientid, i.ingredientname,
+            (SELECT COUNT(DISTINCT ri.recipeid) FROM recipeingredients ri INNER JOIN recipes r USING (recipeid) WHERE ri.ingredientid = i.ingredientid AND r.deleted_at IS NULL AND r.status = 'published') AS popularity
+        FROM (SELECT ingredientid, MIN(rank) AS rank FROM matches GROUP BY ingredientid) m
+        INNER JOIN ingredients i ON i.ingredientid = m.ingredientid
+        ORDER BY m.rank, popularity DESC, LOWER(i.ingredientname)
+        LIMIT $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, prefix, likePrefix(prefix), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := []IngredientSuggestion{}
+	for rows.Next() {
+		var suggestion IngredientSuggestion
+		if err := rows.Scan(&suggestion.ID, &suggestion.Name, &suggestion.RecipeCount); err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	m.suggestions.set(key, suggestions)
+	return suggestions, nil
+}
@@ -0,0 +1,150 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"recipe.athif.com/internal/validator"
+)
+
+// TagCategories are the categories of the controlled tag vocabulary. Tags outside the
+// vocabulary are created on first use in the "free" category.
+var TagCategories = []string{"diet", "course", "label", "free"}
+
+type Tag struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Category    string `json:"category"`
+	RecipeCount int    `json:"recipe_count"`
+}
+
+// NormalizeTags slugifies the given tags, dropping blanks and duplicates, and returns
+// them sorted. A nil slice is returned unchanged so callers can tell "no tags given"
+// from "remove all tags".
+func NormalizeTags(tags []string) []string {
+	if tags == nil {
+		return nil
+	}
+	seen := make(map[string]bool, len(tags))
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = Slugify(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+func ValidateTags(v *validator.Validator, tags []string) {
+	v.Check(len(tags) <= 20, "tags", "must not contain more than 20 tags")
+	for _, tag := range tags {
+		if len(tag) > 50 {
+			v.AddError("tags", "must not contain a tag more than 50 bytes long")
+		}
+		if !validator.Matches(tag, slugRX) {
+			v.AddError("tags", "must contain only lower case letters, digits and single hyphens")
+		}
+	}
+}
+
+type TagModel struct {
+	DB *sql.DB
+}
+
+// GetAll returns every tag in the given category (or all of them when category is
+// empty) with the number of recipes using it, most used first.
+func (m TagModel) GetAll(category string) ([]*Tag, error) {
+	query := `
+        SELECT t.tagid, t.name, t.category, COUNT(rt.recipeid)
+        FROM tags t
+        LEFT JOIN recipe_tags rt ON rt.tagid = t.tagid
+            AND EXISTS (SELECT 1 FROM recipes r WHERE r.recipeid = rt.recipeid AND r.deleted_at IS NULL AND r.status = 'published')
+        WHERE (t.category = $1 OR $1 = '')
+        GROUP BY t.tagid
+        ORDER BY COUNT(rt.recipeid) DESC, t.name ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, category)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []*Tag{}
+	for rows.Next() {
+		var tag Tag
+		err := rows.Scan(&tag.ID, &tag.Name, &tag.Category, &tag.RecipeCount)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, &tag)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// replaceTags overwrites the tags of a recipe, creating free tags for names which
+// aren't in the vocabulary yet.
+func replaceTags(ctx context.Context, db queryer, recipeID int, tags []string) error {
+	_, err := db.ExecContext(ctx, `DELETE FROM recipe_tags WHERE recipeid = $1`, recipeID)
+	if err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+
+	query := `
+        INSERT INTO tags (name)
+        SELECT UNNEST($1::text[])
+        ON CONFLICT (name) DO NOTHING`
+
+	_, err = db.ExecContext(ctx, query, tags)
+	if err != nil {
+		return err
+	}
+
+	query = `
+        INSERT INTO recipe_tags (recipeid, tagid)
+        SELECT $1, tagid FROM tags WHERE name = ANY($2)`
+
+	_, err = db.ExecContext(ctx, query, recipeID, tags)
+	return err
+}
+
+// loadTags fills in the tags of the recipes in byID.
+func loadTags(ctx context.Context, db queryer, ids []int, byID map[int]*Recipe) error {
+	query := `
+    SELECT rt.recipeid, t.name
+    FROM recipe_tags rt
+    INNER JOIN tags t ON t.tagid = rt.tagid
+    WHERE rt.recipeid = ANY($1)
+    ORDER BY rt.recipeid, t.name`
+
+	rows, err := db.QueryContext(ctx, query, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var recipeID int
+		var name string
+		err := rows.Scan(&recipeID, &name)
+		if err != nil {
+			return err
+		}
+		byID[recipeID].Tags = append(byID[recipeID].Tags, name)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,257 @@
+// Package ingredientparse splits free-text ingredient lines such as
+// "1 ½ tbsp olive oil, divided" into a quantity, a unit, an ingredient name, any
+// preparation ("chopped") and a note.
+package ingredientparse
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"recipe.athif.com/internal/data"
+)
+
+// Line is a parsed ingredient line. Quantity is zero when the line doesn't give one,
+// and Unit is empty for counted ingredients such as "2 eggs". For a range such as
+// "2-3 cloves" Quantity holds the lower bound and QuantityMax the upper one. A range
+// whose upper bound isn't above its lower one is not a range, so only its lower bound
+// is kept.
+type Line struct {
+	Quantity    float64 `json:"quantity"`
+	QuantityMax float64 `json:"quantity_max,omitempty"`
+	Unit        string  `json:"unit"`
+	Name        string  `json:"name"`
+	Preparation string  `json:"preparation,omitempty"`
+	Note        string  `json:"note,omitempty"`
+	Raw         string  `json:"raw"`
+}
+
+// Ingredient converts the line to a recipe ingredient. Recipes have nowhere to keep
+// the range, preparation or note, so only the lower bound, unit and name are kept.
+func (l Line) Ingredient() data.Ingredient {
+	return data.Ingredient{IngredientName: l.Name, Quantity: float32(l.Quantity), Unit: l.Unit}
+}
+
+// units maps the spellings of each unit to its canonical abbreviation.
+var units = map[string]string{}
+
+func init() {
+	for canonical, spellings := range map[string][]string{
+		"tsp":     {"tsp", "tsps", "teaspoon", "teaspoons", "t"},
+		"tbsp":    {"tbsp", "tbsps", "tbs", "tbl", "tablespoon", "tablespoons", "T"},
+		"cup":     {"cup", "cups", "c"},
+		"ml":      {"ml", "mls", "millilitre", "millilitres", "milliliter", "milliliters"},
+		"l":       {"l", "litre", "litres", "liter", "liters"},
+		"fl oz":   {"fl oz", "fl. oz", "fluid ounce", "fluid ounces"},
+		"g":       {"g", "gram", "grams", "gr"},
+		"kg":      {"kg", "kgs", "kilogram", "kilograms"},
+		"oz":      {"oz", "ounce", "ounces"},
+		"lb":      {"lb", "lbs", "pound", "pounds"},
+		"pinch":   {"pinch", "pinches"},
+		"dash":    {"dash", "dashes"},
+		"clove":   {"clove", "cloves"},
+		"can":     {"can", "cans", "tin", "tins"},
+		"slice":   {"slice", "slices"},
+		"bunch":   {"bunch", "bunches"},
+		"sprig":   {"sprig", "sprigs"},
+		"piece":   {"piece", "pieces", "pc", "pcs"},
+		"packet":  {"packet", "packets", "pack", "packs", "package", "packages"},
+		"stick":   {"stick", "sticks"},
+		"handful": {"handful", "handfuls"},
+	} {
+		for _, spelling := range spellings {
+			units[spelling] = canonical
+		}
+	}
+}
+
+// fractions maps the unicode vulgar fractions to their ASCII spelling.
+var fractions = map[rune]string{
+	'½': "1/2", '⅓': "1/3", '⅔': "2/3", '¼': "1/4", '¾': "3/4",
+	'⅕': "1/5", '⅖': "2/5", '⅗': "3/5", '⅘': "4/5", '⅙': "1/6",
+	'⅚': "5/6", '⅛': "1/8", '⅜': "3/8", '⅝': "5/8", '⅞': "7/8",
+}
+
+// preparations are the words which describe how an ingredient is prepared rather than
+// what it is, and modifiers are the adverbs which may come before them, as in "finely
+// chopped".
+var (
+	preparations = wordSet("chopped", "diced", "minced", "sliced", "grated", "shredded", "crushed",
+		"peeled", "melted", "softened", "beaten", "cubed", "julienned", "halved", "quartered",
+		"trimmed", "rinsed", "drained", "toasted", "sifted", "mashed", "packed", "deseeded",
+		"seeded", "pitted", "zested", "juiced", "cooked")
+	modifiers = wordSet("finely", "roughly", "coarsely", "thinly", "thickly", "freshly", "lightly", "well")
+)
+
+func wordSet(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, word := range words {
+		set[word] = true
+	}
+	return set
+}
+
+// number matches a mixed number, written "1 1/2" or "1-1/2", a fraction, or a whole
+// number or decimal. A hyphen followed by a fraction is read as a mixed number rather
+// than a range, so "2-3/4" is two and three quarters.
+const number = `\d+(?:\s+|-)\d+/\d+|\d+/\d+|\d+(?:[.,]\d+)?`
+
+var (
+	// quantityRX matches a leading whole number, decimal, fraction or mixed number,
+	// optionally followed by a second one making a range such as "2-3" or "2 to 3".
+	quantityRX = regexp.MustCompile(`^(` + number + `)(?:\s*(?:-|to)\s*(` + number + `))?\s*`)
+	// articleRX matches a leading "a" or "an", which counts as a quantity of one.
+	articleRX = regexp.MustCompile(`(?i)^an?\s+`)
+	// unitRX matches a leading word, or the two-word "fl oz" unit, and an optional dot.
+	unitRX = regexp.MustCompile(`^(fl\.? oz|[A-Za-z]+)\.?(?:\s+|$)`)
+	// parenRX matches a parenthetical, such as the "(14 oz)" in "1 (14 oz) can".
+	parenRX = regexp.MustCompile(`\s*\(([^)]*)\)`)
+	// trailingNoteRX matches the phrases which end a line without being part of the
+	// name, as in "salt to taste" or "water, or as needed".
+	trailingNoteRX = regexp.MustCompile(`(?i)[\s,]+((?:or\s+)?(?:to taste|as needed|for serving|for garnish|optional))\s*$`)
+)
+
+// Parse splits an ingredient line. It never fails: anything it can't make sense of ends
+// up in Name, so a line such as "salt" is just a name.
+func Parse(raw string) Line {
+	line := Line{Raw: raw}
+	s := normalize(raw)
+	var notes, preps []string
+
+	for _, m := range parenRX.FindAllStringSubmatch(s, -1) {
+		if note := strings.TrimSpace(m[1]); note != "" {
+			notes = append(notes, note)
+		}
+	}
+	s = strings.TrimSpace(parenRX.ReplaceAllString(s, ""))
+
+	var trailing string
+	if m := trailingNoteRX.FindStringSubmatch(s); m != nil {
+		trailing = strings.ToLower(m[1])
+		s = s[:len(s)-len(m[0])]
+	}
+
+	if m := quantityRX.FindStringSubmatch(s); m != nil {
+		line.Quantity = parseNumber(m[1])
+		if max := parseNumber(m[2]); max > line.Quantity {
+			line.QuantityMax = max
+		}
+		s = s[len(m[0]):]
+	} else if m := articleRX.FindString(s); m != "" {
+		line.Quantity = 1
+		s = s[len(m):]
+	}
+
+	if m := unitRX.FindStringSubmatch(s); m != nil {
+		// A unit is followed by a name or comes after a quantity, as in "½ cup", so a line
+		// which is just "cloves" is the spice. So is "2 cloves", which counts them.
+		unit, ok := lookupUnit(m[1])
+		named := len(m[0]) < len(s)
+		if ok && (named || line.Quantity > 0 && unit != "clove") {
+			line.Unit = unit
+			s = s[len(m[0]):]
+			s = strings.TrimPrefix(s, "of ")
+		}
+	}
+
+	name, rest, _ := strings.Cut(s, ",")
+	for _, part := range strings.Split(rest, ",") {
+		part = strings.TrimSpace(part)
+		switch {
+		case part == "":
+		case isPreparation(part):
+			preps = append(preps, part)
+		default:
+			notes = append(notes, part)
+		}
+	}
+
+	// Leading preparation, as in "chopped onion" or "finely chopped onion".
+	words := strings.Fields(name)
+	for i := 0; i < len(words)-1; i++ {
+		word := strings.ToLower(words[i])
+		if preparations[word] {
+			preps = append([]string{strings.Join(words[:i+1], " ")}, preps...)
+			words = words[i+1:]
+			break
+		}
+		if !modifiers[word] {
+			break
+		}
+	}
+
+	if trailing != "" {
+		notes = append(notes, trailing)
+	}
+	line.Name = strings.Join(words, " ")
+	line.Preparation = strings.Join(preps, ", ")
+	line.Note = strings.Join(notes, ", ")
+	return line
+}
+
+// normalize spells unicode fractions and dashes in ASCII and collapses white space,
+// so "1½" becomes "1 1/2".
+func normalize(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if fraction, ok := fractions[r]; ok {
+			b.WriteString(" " + fraction + " ")
+			continue
+		}
+		switch r {
+		case '⁄':
+			r = '/'
+		case '–', '—':
+			r = '-'
+		}
+		b.WriteRune(r)
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// isPreparation reports whether a phrase after the name, such as "finely chopped",
+// describes preparation.
+func isPreparation(phrase string) bool {
+	for _, word := range strings.Fields(strings.ToLower(phrase)) {
+		if preparations[word] {
+			return true
+		}
+		if !modifiers[word] {
+			return false
+		}
+	}
+	return false
+}
+
+// lookupUnit matches word against the known units. Single letters are case sensitive,
+// since "T" is a tablespoon but "t" a teaspoon.
+func lookupUnit(word string) (string, bool) {
+	if unit, ok := units[word]; ok {
+		return unit, true
+	}
+	if len(word) == 1 {
+		return "", false
+	}
+	word = strings.ToLower(strings.ReplaceAll(word, ".", ""))
+	unit, ok := units[word]
+	return unit, ok
+}
+
+// parseNumber reads "2", "2.5", "2,5", "1/2", "2 1/2" or "2-1/2". It returns zero for
+// an empty string.
+func parseNumber(s string) float64 {
+	var total float64
+	for _, part := range strings.Fields(strings.ReplaceAll(s, "-", " ")) {
+		if num, den, ok := strings.Cut(part, "/"); ok {
+			n, _ := strconv.ParseFloat(num, 64)
+			d, _ := strconv.ParseFloat(den, 64)
+			if d != 0 {
+				total += n / d
+			}
+			continue
+		}
+		f, _ := strconv.ParseFloat(strings.ReplaceAll(part, ",", "."), 64)
+		total += f
+	}
+	return total
+}
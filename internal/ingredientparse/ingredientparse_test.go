@@ -0,0 +1,44 @@
+package ingredientparse
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want Line
+	}{
+		{"salt", Line{Name: "salt"}},
+		{"2 eggs", Line{Quantity: 2, Name: "eggs"}},
+		{"an onion, finely chopped", Line{Quantity: 1, Name: "onion", Preparation: "finely chopped"}},
+		{"1 1/2 cups flour", Line{Quantity: 1.5, Unit: "cup", Name: "flour"}},
+		{"1-1/2 cups milk", Line{Quantity: 1.5, Unit: "cup", Name: "milk"}},
+		{"2-3/4 cups milk", Line{Quantity: 2.75, Unit: "cup", Name: "milk"}},
+		{"1½ cups flour", Line{Quantity: 1.5, Unit: "cup", Name: "flour"}},
+		{"½ cup sugar", Line{Quantity: 0.5, Unit: "cup", Name: "sugar"}},
+		{"½ cup", Line{Quantity: 0.5, Unit: "cup"}},
+		{"¼ tsp salt", Line{Quantity: 0.25, Unit: "tsp", Name: "salt"}},
+		{"2.5 kg potatoes", Line{Quantity: 2.5, Unit: "kg", Name: "potatoes"}},
+		{"2-3 cloves garlic, minced", Line{Quantity: 2, QuantityMax: 3, Unit: "clove", Name: "garlic", Preparation: "minced"}},
+		{"2 to 3 tbsp butter", Line{Quantity: 2, QuantityMax: 3, Unit: "tbsp", Name: "butter"}},
+		{"1 1/2-2 cups stock", Line{Quantity: 1.5, QuantityMax: 2, Unit: "cup", Name: "stock"}},
+		{"3-2 cups rice", Line{Quantity: 3, Unit: "cup", Name: "rice"}},
+		{"cloves", Line{Name: "cloves"}},
+		{"2 cloves", Line{Quantity: 2, Name: "cloves"}},
+		{"1 T sugar", Line{Quantity: 1, Unit: "tbsp", Name: "sugar"}},
+		{"1 t sugar", Line{Quantity: 1, Unit: "tsp", Name: "sugar"}},
+		{"1 (14 oz) can tomatoes", Line{Quantity: 1, Unit: "can", Name: "tomatoes", Note: "14 oz"}},
+		{"1 ½ tbsp olive oil, divided", Line{Quantity: 1.5, Unit: "tbsp", Name: "olive oil", Note: "divided"}},
+		{"salt to taste", Line{Name: "salt", Note: "to taste"}},
+		{"1 cup water, or as needed", Line{Quantity: 1, Unit: "cup", Name: "water", Note: "or as needed"}},
+		{"2 cups of milk, warmed, for serving", Line{Quantity: 2, Unit: "cup", Name: "milk", Note: "warmed, for serving"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			tt.want.Raw = tt.raw
+			if got := Parse(tt.raw); got != tt.want {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+// Package recommend ranks recipes for a user from a profile of the recipes they liked.
+//
+// The profile records how much of what the user liked comes from each cuisine and
+// difficulty, how often each ingredient appears in it and how long those recipes take.
+// A candidate recipe scores well when it matches on each of these, with ingredients
+// weighted by how rare they are among the candidates, so that sharing a staple says
+// little about taste. The ranking is then reordered so that it isn't all one cuisine.
+package recommend
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// The weights of each part of the score, which add up to one.
+const (
+	cuisineWeight    = 0.35
+	ingredientWeight = 0.4
+	difficultyWeight = 0.15
+	timeWeight       = 0.1
+)
+
+// diversityDecay scales the score of a candidate once for each recipe of its cuisine
+// already recommended.
+const diversityDecay = 0.6
+
+// The thresholds above which a part of the score is mentioned in an explanation.
+const (
+	explainCuisine     = 0.2
+	explainDifficulty  = 0.5
+	explainTime        = 0.75
+	explainIngredients = 2
+)
+
+type Ingredient struct {
+	ID   int
+	Name string
+}
+
+// Recipe is what a recommendation is computed from. TotalTime is in minutes.
+type Recipe struct {
+	ID          int
+	CuisineID   int
+	Cuisine     string
+	Difficulty  string
+	TotalTime   int
+	Ingredients []Ingredient
+}
+
+// Liked is a recipe the user liked. Weight is how strongly: a favorite counts for one,
+// and higher ratings for more.
+type Liked struct {
+	Recipe
+	Weight float64
+}
+
+// Recommendation is a recipe chosen for the user along with why it was chosen.
+type Recommendation struct {
+	RecipeID    int
+	Score       float64
+	Explanation string
+}
+
+// Profile is a user's taste, as learned from the recipes they liked.
+type Profile struct {
+	weight       float64
+	cuisines     map[int]float64
+	cuisineLikes map[int]int
+	difficulties map[string]float64
+	ingredients  map[int]float64
+	time         float64
+}
+
+// NewProfile builds a profile from the recipes the user liked. Recipes with no weight
+// are ignored.
+func NewProfile(liked []Liked) *Profile {
+	p := &Profile{
+		cuisines:     map[int]float64{},
+		cuisineLikes: map[int]int{},
+		difficulties: map[string]float64{},
+		ingredients:  map[int]float64{},
+	}
+	var time float64
+	for _, recipe := range liked {
+		if recipe.Weight <= 0 {
+			continue
+		}
+		p.weight += recipe.Weight
+		p.cuisines[recipe.CuisineID] += recipe.Weight
+		p.cuisineLikes[recipe.CuisineID]++
+		p.difficulties[recipe.Difficulty] += recipe.Weight
+		for _, ingredient := range distinct(recipe.Ingredients) {
+			p.ingredients[ingredient.ID] += recipe.Weight
+		}
+		time += recipe.Weight * float64(recipe.TotalTime)
+	}
+	if p.weight > 0 {
+		p.time = time / p.weight
+	}
+	return p
+}
+
+// Empty reports whether the profile was built from no liked recipes, in which case it
+// recommends nothing.
+func (p *Profile) Empty() bool {
+	return p.weight == 0
+}
+
+// scored is a candidate with the parts of its score kept for the explanation.
+type scored struct {
+	recipe                           *Recipe
+	cuisine, ingredients, difficulty float64
+	time, score                      float64
+	shared                           []Ingredient
+}
+
+// Recommend returns up to n of the candidates, best first, reordered for variety of
+// cuisine. Candidates sharing neither a cuisine nor an ingredient with the profile are
+// left out, and candidates should already leave out the recipes the user has seen.
+func (p *Profile) Recommend(candidates []Recipe, n int) []Recommendation {
+	if p.Empty() || n <= 0 {
+		return []Recommendation{}
+	}
+
+	// Ingredients are weighted by their inverse document frequency among the
+	// candidates.
+	df := map[int]int{}
+	for _, recipe := range candidates {
+		for _, ingredient := range distinct(recipe.Ingredients) {
+			df[ingredient.ID]++
+		}
+	}
+	idf := func(id int) float64 {
+		return math.Log(1 + float64(len(candidates))/float64(df[id]))
+	}
+
+	pool := make([]*scored, 0, len(candidates))
+	for i := range candidates {
+		recipe := &candidates[i]
+		s := &scored{
+			recipe:     recipe,
+			cuisine:    p.cuisines[recipe.CuisineID] / p.weight,
+			difficulty: p.difficulties[recipe.Difficulty] / p.weight,
+			time:       1 / (1 + math.Abs(float64(recipe.TotalTime)-p.time)/math.Max(p.time, 30)),
+		}
+
+		var matched, total float64
+		for _, ingredient := range distinct(recipe.Ingredients) {
+			w := idf(ingredient.ID)
+			total += w
+			if affinity := p.ingredients[ingredient.ID] / p.weight; affinity > 0 {
+				matched += w * affinity
+				s.shared = append(s.shared, ingredient)
+			}
+		}
+		if total > 0 {
+			s.ingredients = matched / total
+		}
+		// Matching only on difficulty or time says too little to recommend a recipe.
+		if s.cuisine == 0 && s.ingredients == 0 {
+			continue
+		}
+		// The ingredients most telling of the user's taste come first.
+		sort.SliceStable(s.shared, func(i, j int) bool {
+			a, b := s.shared[i].ID, s.shared[j].ID
+			return idf(a)*p.ingredients[a] > idf(b)*p.ingredients[b]
+		})
+
+		s.score = cuisineWeight*s.cuisine + ingredientWeight*s.ingredients +
+			difficultyWeight*s.difficulty + timeWeight*s.time
+		pool = append(pool, s)
+	}
+
+	// Pick greedily, discounting each candidate by how many recipes of its cuisine have
+	// been picked already.
+	picked := map[int]int{}
+	recommendations := []Recommendation{}
+	for len(recommendations) < n && len(pool) > 0 {
+		best, bestScore := -1, 0.0
+		for i, s := range pool {
+			adjusted := s.score * math.Pow(diversityDecay, float64(picked[s.recipe.CuisineID]))
+			if best == -1 || adjusted > bestScore || (adjusted == bestScore && s.recipe.ID < pool[best].recipe.ID) {
+				best, bestScore = i, adjusted
+			}
+		}
+		s := pool[best]
+		pool = append(pool[:best], pool[best+1:]...)
+		picked[s.recipe.CuisineID]++
+
+		recommendations = append(recommendations, Recommendation{
+			RecipeID:    s.recipe.ID,
+			Score:       s.score,
+			Explanation: p.explain(s),
+		})
+	}
+	return recommendations
+}
+
+// explain says which parts of the profile the candidate matched.
+func (p *Profile) explain(s *scored) string {
+	var reasons []string
+	if s.cuisine >= explainCuisine {
+		likes := p.cuisineLikes[s.recipe.CuisineID]
+		noun := "recipes"
+		if likes == 1 {
+			noun = "recipe"
+		}
+		reasons = append(reasons, fmt.Sprintf("you liked %d %s %s", likes, s.recipe.Cuisine, noun))
+	}
+	if len(s.shared) > 0 {
+		names := make([]string, 0, explainIngredients)
+		for _, ingredient := range s.shared[:min(len(s.shared), explainIngredients)] {
+			names = append(names, ingredient.Name)
+		}
+		reasons = append(reasons, fmt.Sprintf("it uses %s from recipes you liked", join(names)))
+	}
+	if s.difficulty >= explainDifficulty {
+		reasons = append(reasons, fmt.Sprintf("it is %s like most recipes you liked", strings.ToLower(s.recipe.Difficulty)))
+	}
+	if s.time >= explainTime {
+		reasons = append(reasons, "it takes about as long as the recipes you liked")
+	}
+	if len(reasons) == 0 {
+		return "Recommended as the closest match to the recipes you liked."
+	}
+	return "Recommended because " + join(reasons) + "."
+}
+
+// join lists items as "a", "a and b" or "a, b and c".
+func join(items []string) string {
+	if len(items) <= 1 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+}
+
+// distinct returns the ingredients without repeats, keeping their order.
+func distinct(ingredients []Ingredient) []Ingredient {
+	seen := make(map[int]bool, len(ingredients))
+	unique := make([]Ingredient, 0, len(ingredients))
+	for _, ingredient := range ingredients {
+		if !seen[ingredient.ID] {
+			seen[ingredient.ID] = true
+			unique = append(unique, ingredient)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,193 @@
+package recommend
+
+import (
+	"reflect"
+	"testing"
+)
+
+var (
+	tomato  = Ingredient{ID: 1, Name: "tomato"}
+	basil   = Ingredient{ID: 2, Name: "basil"}
+	garlic  = Ingredient{ID: 3, Name: "garlic"}
+	saffron = Ingredient{ID: 4, Name: "saffron"}
+	rice    = Ingredient{ID: 5, Name: "rice"}
+	cumin   = Ingredient{ID: 6, Name: "cumin"}
+	tofu    = Ingredient{ID: 7, Name: "tofu"}
+)
+
+func italian(id int, ingredients ...Ingredient) Recipe {
+	return Recipe{ID: id, CuisineID: 1, Cuisine: "Italian", Difficulty: "Easy", TotalTime: 30, Ingredients: ingredients}
+}
+
+func indian(id int, ingredients ...Ingredient) Recipe {
+	return Recipe{ID: id, CuisineID: 2, Cuisine: "Indian", Difficulty: "Medium", TotalTime: 60, Ingredients: ingredients}
+}
+
+func ids(recommendations []Recommendation) []int {
+	ids := []int{}
+	for _, r := range recommendations {
+		ids = append(ids, r.RecipeID)
+	}
+	return ids
+}
+
+func TestEmpty(t *testing.T) {
+	tests := []struct {
+		name  string
+		liked []Liked
+		want  bool
+	}{
+		{"nothing liked", nil, true},
+		{"only unweighted likes", []Liked{{Recipe: italian(1, tomato), Weight: 0}, {Recipe: italian(2, basil), Weight: -1}}, true},
+		{"one like", []Liked{{Recipe: italian(1, tomato), Weight: 1}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProfile(tt.liked)
+			if got := p.Empty(); got != tt.want {
+				t.Errorf("Empty() = %v, want %v", got, tt.want)
+			}
+			if got := p.Recommend([]Recipe{italian(10, tomato)}, 5); tt.want && len(got) != 0 {
+				t.Errorf("an empty profile recommended %v", got)
+			}
+		})
+	}
+}
+
+func TestRecommend(t *testing.T) {
+	profile := NewProfile([]Liked{
+		{Recipe: italian(1, tomato, basil, garlic), Weight: 1},
+		{Recipe: italian(2, tomato, garlic, garlic), Weight: 1.5},
+	})
+
+	t.Run("closest match first", func(t *testing.T) {
+		// Ingredients count by the share of the candidate's which were liked.
+		candidates := []Recipe{
+			italian(10, tomato, tofu, rice),
+			italian(11, tomato, basil, garlic),
+			indian(12, garlic, cumin),
+		}
+		got := ids(profile.Recommend(candidates, 10))
+		if want := []int{11, 10, 12}; !reflect.DeepEqual(got, want) {
+			t.Errorf("Recommend() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("no cuisine or ingredient in common", func(t *testing.T) {
+		// Recipe 20 matches on difficulty and time alone.
+		candidates := []Recipe{
+			{ID: 20, CuisineID: 3, Cuisine: "Japanese", Difficulty: "Easy", TotalTime: 30, Ingredients: []Ingredient{tofu}},
+			indian(21, saffron, rice),
+		}
+		if got := profile.Recommend(candidates, 10); len(got) != 0 {
+			t.Errorf("Recommend() = %v, want nothing", got)
+		}
+	})
+
+	t.Run("at most n", func(t *testing.T) {
+		candidates := []Recipe{italian(10, tomato), italian(11, basil), italian(12, garlic)}
+		if got := profile.Recommend(candidates, 2); len(got) != 2 {
+			t.Errorf("Recommend(n=2) returned %d recommendations", len(got))
+		}
+		if got := profile.Recommend(candidates, 0); len(got) != 0 {
+			t.Errorf("Recommend(n=0) = %v, want nothing", got)
+		}
+	})
+
+	t.Run("ties by ID", func(t *testing.T) {
+		candidates := []Recipe{italian(12, tomato), italian(10, tomato), italian(11, tomato)}
+		got := ids(profile.Recommend(candidates, 10))
+		if want := []int{10, 11, 12}; !reflect.DeepEqual(got, want) {
+			t.Errorf("Recommend() = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestRecommendDiversity(t *testing.T) {
+	// Mostly Italian, with a little Indian.
+	profile := NewProfile([]Liked{
+		{Recipe: italian(1, tomato, basil), Weight: 2},
+		{Recipe: italian(2, tomato, garlic), Weight: 2},
+		{Recipe: indian(3, garlic, cumin), Weight: 1},
+	})
+	candidates := []Recipe{
+		italian(10, tomato, basil),
+		italian(11, tomato, basil),
+		italian(12, tomato, garlic),
+		indian(13, garlic, cumin),
+	}
+
+	recommendations := profile.Recommend(candidates, 3)
+	got := ids(recommendations)
+	// The Indian recipe scores below every Italian one, but the third Italian recipe is
+	// discounted twice over.
+	if len(got) != 3 || got[0] == 13 {
+		t.Fatalf("Recommend() = %v, want an Italian recipe first", got)
+	}
+	var indianPicked bool
+	for _, id := range got {
+		indianPicked = indianPicked || id == 13
+	}
+	if !indianPicked {
+		t.Errorf("Recommend() = %v, want the Indian recipe among them", got)
+	}
+
+	// Scores are reported before the discount.
+	for _, r := range recommendations {
+		if r.RecipeID == 13 && r.Score >= recommendations[0].Score {
+			t.Errorf("the Indian recipe scored %v, not below %v", r.Score, recommendations[0].Score)
+		}
+	}
+}
+
+func TestExplanation(t *testing.T) {
+	profile := NewProfile([]Liked{
+		{Recipe: italian(1, tomato, basil, saffron), Weight: 1},
+		{Recipe: italian(2, tomato, garlic), Weight: 1},
+	})
+	candidates := []Recipe{
+		italian(10, tomato, saffron, rice),
+		{ID: 11, CuisineID: 3, Cuisine: "Japanese", Difficulty: "Advanced", TotalTime: 300, Ingredients: []Ingredient{tomato, tofu}},
+	}
+
+	// Tomato comes first: saffron is rarer among the candidates, but tomato was in both
+	// liked recipes.
+	got := map[int]string{}
+	for _, r := range profile.Recommend(candidates, 10) {
+		got[r.RecipeID] = r.Explanation
+	}
+	want := map[int]string{
+		10: "Recommended because you liked 2 Italian recipes, it uses tomato and saffron from recipes you liked, " +
+			"it is easy like most recipes you liked and it takes about as long as the recipes you liked.",
+		11: "Recommended because it uses tomato from recipes you liked.",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("explanations = %q, want %q", got, want)
+	}
+
+	single := NewProfile([]Liked{{Recipe: indian(3, cumin), Weight: 1}})
+	recommendations := single.Recommend([]Recipe{indian(12, rice)}, 1)
+	if len(recommendations) != 1 {
+		t.Fatalf("Recommend() = %v, want one recommendation", recommendations)
+	}
+	if got, want := recommendations[0].Explanation, "Recommended because you liked 1 Indian recipe, it is medium like most recipes you liked and it takes about as long as the recipes you liked."; got != want {
+		t.Errorf("explanation = %q, want %q", got, want)
+	}
+}
+
+func TestJoin(t *testing.T) {
+	tests := []struct {
+		items []string
+		want  string
+	}{
+		{nil, ""},
+		{[]string{"a"}, "a"},
+		{[]string{"a", "b"}, "a and b"},
+		{[]string{"a", "b", "c"}, "a, b and c"},
+	}
+	for _, tt := range tests {
+		if got := join(tt.items); got != tt.want {
+			t.Errorf("join(%q) = %q, want %q", tt.items, got, tt.want)
+		}
+	}
+}
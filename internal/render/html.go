@@ -0,0 +1,26 @@
+package render
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+
+	"recipe.athif.com/internal/data"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+var htmlTemplate = template.Must(template.New("recipe.html").Funcs(template.FuncMap{
+	"ingredientLine": IngredientLine,
+}).ParseFS(templateFS, "templates/recipe.html"))
+
+// HTML writes the recipe as a self-contained, print-friendly HTML page.
+func HTML(recipe *data.Recipe) ([]byte, error) {
+	var b bytes.Buffer
+	err := htmlTemplate.Execute(&b, recipe)
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
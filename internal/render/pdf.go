@@ -0,0 +1,73 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+	"recipe.athif.com/internal/data"
+)
+
+// PDF writes the recipe as an A4 PDF using the built-in Helvetica font, so no font
+// files are needed. Text is converted to the Windows-1252 encoding those fonts use and
+// characters outside it are lost. Images aren't included, since that would mean
+// fetching them.
+func PDF(recipe *data.Recipe) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(20, 20, 20)
+	pdf.SetAutoPageBreak(true, 20)
+	pdf.SetTitle(recipe.Title, true)
+	pdf.AddPage()
+
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	width, _ := pdf.GetPageSize()
+	left, _, right, _ := pdf.GetMargins()
+	textWidth := width - left - right
+
+	pdf.SetFont("Helvetica", "B", 20)
+	pdf.MultiCell(textWidth, 9, tr(recipe.Title), "", "L", false)
+	pdf.Ln(2)
+
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.SetTextColor(85, 85, 85)
+	meta := fmt.Sprintf("%s  |  %s  |  Prep %s  |  Cook %s  |  Total %s",
+		recipe.CuisineName, recipe.Difficulty, recipe.PrepTime.Human(), recipe.CookTime.Human(), recipe.TotalTime.Human())
+	pdf.MultiCell(textWidth, 5, tr(meta), "", "L", false)
+	if len(recipe.Tags) > 0 {
+		pdf.MultiCell(textWidth, 5, tr(strings.Join(recipe.Tags, ", ")), "", "L", false)
+	}
+	pdf.SetTextColor(0, 0, 0)
+
+	heading := func(text string) {
+		pdf.Ln(6)
+		pdf.SetFont("Helvetica", "B", 14)
+		pdf.CellFormat(textWidth, 8, text, "B", 1, "L", false, 0, "")
+		pdf.Ln(2)
+		pdf.SetFont("Helvetica", "", 11)
+	}
+
+	heading("Ingredients")
+	for _, ingredient := range recipe.Ingredients {
+		pdf.CellFormat(6, 6, tr("•"), "", 0, "L", false, 0, "")
+		pdf.MultiCell(textWidth-6, 6, tr(IngredientLine(ingredient)), "", "L", false)
+	}
+
+	heading("Method")
+	for i, step := range recipe.Steps {
+		text := step.Text
+		if step.Duration > 0 {
+			text += fmt.Sprintf(" (%s)", step.Duration.Human())
+		}
+		pdf.CellFormat(8, 6, fmt.Sprintf("%d.", i+1), "", 0, "L", false, 0, "")
+		pdf.MultiCell(textWidth-8, 6, tr(text), "", "L", false)
+		pdf.Ln(1)
+	}
+
+	var b bytes.Buffer
+	err := pdf.Output(&b)
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,120 @@
+// Package render writes recipes in formats other than the API's own JSON: schema.org
+// JSON-LD, Markdown, a printable HTML card and PDF.
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"recipe.athif.com/internal/data"
+)
+
+// IngredientLine formats an ingredient as it would be written in a recipe, such as
+// "2.5 cup flour" or "3 eggs". Lines without a quantity are just the name.
+func IngredientLine(ingredient data.Ingredient) string {
+	parts := make([]string, 0, 3)
+	if ingredient.Quantity > 0 {
+		parts = append(parts, strconv.FormatFloat(float64(ingredient.Quantity), 'f', -1, 32))
+	}
+	if ingredient.Unit != "" {
+		parts = append(parts, ingredient.Unit)
+	}
+	parts = append(parts, ingredient.IngredientName)
+	return strings.Join(parts, " ")
+}
+
+// JSONLD writes the recipe as a schema.org/Recipe JSON-LD document, as embedded in
+// pages for search engines.
+func JSONLD(recipe *data.Recipe) ([]byte, error) {
+	type howToStep struct {
+		Type     string `json:"@type"`
+		Position int    `json:"position"`
+		Text     string `json:"text"`
+		Image    string `json:"image,omitempty"`
+	}
+
+	ingredients := make([]string, len(recipe.Ingredients))
+	for i, ingredient := range recipe.Ingredients {
+		ingredients[i] = IngredientLine(ingredient)
+	}
+	steps := make([]howToStep, len(recipe.Steps))
+	for i, step := range recipe.Steps {
+		steps[i] = howToStep{Type: "HowToStep", Position: step.Position, Text: step.Text, Image: step.ImageLink}
+	}
+
+	doc := struct {
+		Context            string      `json:"@context"`
+		Type               string      `json:"@type"`
+		Name               string      `json:"name"`
+		Image              string      `json:"image,omitempty"`
+		RecipeCuisine      string      `json:"recipeCuisine,omitempty"`
+		Keywords           string      `json:"keywords,omitempty"`
+		PrepTime           string      `json:"prepTime"`
+		CookTime           string      `json:"cookTime"`
+		TotalTime          string      `json:"totalTime"`
+		RecipeIngredient   []string    `json:"recipeIngredient"`
+		RecipeInstructions []howToStep `json:"recipeInstructions"`
+		DateModified       string      `json:"dateModified,omitempty"`
+	}{
+		Context:            "https://schema.org",
+		Type:               "Recipe",
+		Name:               recipe.Title,
+		Image:              recipe.ImageLink,
+		RecipeCuisine:      recipe.CuisineName,
+		Keywords:           strings.Join(recipe.Tags, ", "),
+		PrepTime:           recipe.PrepTime.ISO8601(),
+		CookTime:           recipe.CookTime.ISO8601(),
+		TotalTime:          recipe.TotalTime.ISO8601(),
+		RecipeIngredient:   ingredients,
+		RecipeInstructions: steps,
+	}
+	if !recipe.UpdatedAt.IsZero() {
+		doc.DateModified = recipe.UpdatedAt.UTC().Format(time.RFC3339)
+	}
+
+	js, err := json.MarshalIndent(doc, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	return append(js, '\n'), nil
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "<", `\<`, "#", `\#`,
+)
+
+// Markdown writes the recipe as a Markdown document.
+func Markdown(recipe *data.Recipe) []byte {
+	var b bytes.Buffer
+	esc := markdownEscaper.Replace
+
+	fmt.Fprintf(&b, "# %s\n\n", esc(recipe.Title))
+	fmt.Fprintf(&b, "**Cuisine:** %s · **Difficulty:** %s  \n", esc(recipe.CuisineName), recipe.Difficulty)
+	fmt.Fprintf(&b, "**Prep:** %s · **Cook:** %s · **Total:** %s\n\n", recipe.PrepTime.Human(), recipe.CookTime.Human(), recipe.TotalTime.Human())
+	if len(recipe.Tags) > 0 {
+		fmt.Fprintf(&b, "*%s*\n\n", esc(strings.Join(recipe.Tags, ", ")))
+	}
+	if recipe.ImageLink != "" {
+		fmt.Fprintf(&b, "![%s](<%s>)\n\n", esc(recipe.Title), recipe.ImageLink)
+	}
+
+	b.WriteString("## Ingredients\n\n")
+	for _, ingredient := range recipe.Ingredients {
+		fmt.Fprintf(&b, "- %s\n", esc(IngredientLine(ingredient)))
+	}
+
+	b.WriteString("\n## Method\n\n")
+	for i, step := range recipe.Steps {
+		text := strings.ReplaceAll(esc(step.Text), "\n", "\n   ")
+		fmt.Fprintf(&b, "%d. %s", i+1, text)
+		if step.Duration > 0 {
+			fmt.Fprintf(&b, " *(%s)*", step.Duration.Human())
+		}
+		b.WriteString("\n")
+	}
+	return b.Bytes()
+}
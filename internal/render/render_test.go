@@ -0,0 +1,199 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"recipe.athif.com/internal/data"
+)
+
+func testRecipe() *data.Recipe {
+	return &data.Recipe{
+		Title:       "Pasta *al* pomodoro",
+		PrepTime:    10,
+		CookTime:    80,
+		TotalTime:   90,
+		Difficulty:  data.DifficultyEasy,
+		CuisineName: "Italian",
+		Ingredients: []data.Ingredient{
+			{IngredientName: "spaghetti", Quantity: 200, Unit: "g"},
+			{IngredientName: "tomatoes", Quantity: 2.5, Unit: "cup"},
+			{IngredientName: "salt"},
+		},
+		Steps: []data.Step{
+			{Position: 1, Text: "Boil the pasta.", Duration: 10},
+			{Position: 2, Text: "Simmer the sauce.\nStir often.", Duration: 60},
+			{Position: 3, Text: "Serve."},
+		},
+		Tags:      []string{"quick", "vegetarian"},
+		ImageLink: "https://example.com/pasta.jpg",
+		UpdatedAt: time.Date(2024, 5, 1, 12, 30, 0, 0, time.FixedZone("CEST", 2*60*60)),
+	}
+}
+
+func TestIngredientLine(t *testing.T) {
+	tests := []struct {
+		ingredient data.Ingredient
+		want       string
+	}{
+		{data.Ingredient{IngredientName: "flour", Quantity: 2.5, Unit: "cup"}, "2.5 cup flour"},
+		{data.Ingredient{IngredientName: "eggs", Quantity: 3}, "3 eggs"},
+		{data.Ingredient{IngredientName: "milk", Quantity: 0.1, Unit: "l"}, "0.1 l milk"},
+		{data.Ingredient{IngredientName: "salt", Unit: "pinch"}, "pinch salt"},
+		{data.Ingredient{IngredientName: "pepper"}, "pepper"},
+	}
+	for _, tt := range tests {
+		if got := IngredientLine(tt.ingredient); got != tt.want {
+			t.Errorf("IngredientLine(%+v) = %q, want %q", tt.ingredient, got, tt.want)
+		}
+	}
+}
+
+func TestJSONLD(t *testing.T) {
+	js, err := JSONLD(testRecipe())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(js, &doc); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"@context":         "https://schema.org",
+		"@type":            "Recipe",
+		"name":             "Pasta *al* pomodoro",
+		"image":            "https://example.com/pasta.jpg",
+		"recipeCuisine":    "Italian",
+		"keywords":         "quick, vegetarian",
+		"prepTime":         "PT10M",
+		"cookTime":         "PT1H20M",
+		"totalTime":        "PT1H30M",
+		"recipeIngredient": []any{"200 g spaghetti", "2.5 cup tomatoes", "salt"},
+		"recipeInstructions": []any{
+			map[string]any{"@type": "HowToStep", "position": 1.0, "text": "Boil the pasta."},
+			map[string]any{"@type": "HowToStep", "position": 2.0, "text": "Simmer the sauce.\nStir often."},
+			map[string]any{"@type": "HowToStep", "position": 3.0, "text": "Serve."},
+		},
+		"dateModified": "2024-05-01T10:30:00Z",
+	}
+	if !reflect.DeepEqual(doc, want) {
+		t.Errorf("JSONLD() = %s", js)
+	}
+
+	// Optional fields are left out, and lists are empty rather than null.
+	js, err = JSONLD(&data.Recipe{Title: "Water"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc = nil
+	if err := json.Unmarshal(js, &doc); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"image", "recipeCuisine", "keywords", "dateModified"} {
+		if _, ok := doc[key]; ok {
+			t.Errorf("JSONLD() of a bare recipe has %q", key)
+		}
+	}
+	for _, key := range []string{"recipeIngredient", "recipeInstructions"} {
+		if list, ok := doc[key].([]any); !ok || len(list) != 0 {
+			t.Errorf("JSONLD() of a bare recipe has %q = %v, want []", key, doc[key])
+		}
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	want := "# Pasta \\*al\\* pomodoro\n" +
+		"\n" +
+		"**Cuisine:** Italian · **Difficulty:** Easy  \n" +
+		"**Prep:** 10 mins · **Cook:** 1 hr 20 mins · **Total:** 1 hr 30 mins\n" +
+		"\n" +
+		"*quick, vegetarian*\n" +
+		"\n" +
+		"![Pasta \\*al\\* pomodoro](<https://example.com/pasta.jpg>)\n" +
+		"\n" +
+		"## Ingredients\n" +
+		"\n" +
+		"- 200 g spaghetti\n" +
+		"- 2.5 cup tomatoes\n" +
+		"- salt\n" +
+		"\n" +
+		"## Method\n" +
+		"\n" +
+		"1. Boil the pasta. *(10 mins)*\n" +
+		"2. Simmer the sauce.\n" +
+		"   Stir often. *(1 hr)*\n" +
+		"3. Serve.\n"
+
+	if got := string(Markdown(testRecipe())); got != want {
+		t.Errorf("Markdown() =\n%s\nwant\n%s", got, want)
+	}
+
+	recipe := &data.Recipe{Title: "# [Not](a link) <b>_really_</b> `code` \\"}
+	got := string(Markdown(recipe))
+	title, _, _ := strings.Cut(got, "\n")
+	if want := "# \\# \\[Not\\](a link) \\<b>\\_really\\_\\</b> \\`code\\` \\\\"; title != want {
+		t.Errorf("Markdown() title = %q, want %q", title, want)
+	}
+}
+
+func TestHTML(t *testing.T) {
+	page, err := HTML(testRecipe())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"<title>Pasta *al* pomodoro</title>",
+		"Italian &middot; Easy",
+		"Prep 10 mins &middot; Cook 1 hr 20 mins &middot; Total 1 hr 30 mins",
+		"<span>quick</span><span>vegetarian</span>",
+		`<img src="https://example.com/pasta.jpg" alt="Pasta *al* pomodoro">`,
+		"<li>2.5 cup tomatoes</li>",
+		`<li>Boil the pasta. <span class="duration">(10 mins)</span></li>`,
+		"<li>Serve.</li>",
+	} {
+		if !bytes.Contains(page, []byte(want)) {
+			t.Errorf("HTML() doesn't contain %q", want)
+		}
+	}
+
+	// Text and links from the recipe can't inject markup or scripts.
+	page, err = HTML(&data.Recipe{
+		Title:     `<script>alert("hi")</script>`,
+		ImageLink: `javascript:alert("hi")`,
+		Steps:     []data.Step{{Text: "<b>bold</b>"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, unwanted := range []string{"<script>", "javascript:", "<b>"} {
+		if bytes.Contains(page, []byte(unwanted)) {
+			t.Errorf("HTML() contains %q", unwanted)
+		}
+	}
+	if !bytes.Contains(page, []byte("&lt;script&gt;")) {
+		t.Error("HTML() doesn't escape the title")
+	}
+	if !bytes.Contains(page, []byte("#ZgotmplZ")) {
+		t.Error("HTML() doesn't replace the unsafe image link")
+	}
+}
+
+func TestPDF(t *testing.T) {
+	for _, recipe := range []*data.Recipe{
+		testRecipe(),
+		{Title: "Crème brûlée — 日本語"},
+	} {
+		b, err := PDF(recipe)
+		if err != nil {
+			t.Fatalf("PDF(%q): %v", recipe.Title, err)
+		}
+		if !bytes.HasPrefix(b, []byte("%PDF-")) || !bytes.Contains(b, []byte("%%EOF")) {
+			t.Errorf("PDF(%q) isn't a complete PDF", recipe.Title)
+		}
+	}
+}
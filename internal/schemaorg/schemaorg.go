@@ -0,0 +1,270 @@
+// Package schemaorg extracts recipes from schema.org/Recipe JSON-LD, either on its own
+// or embedded in an HTML page, as published by most recipe sites.
+package schemaorg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	nethtml "golang.org/x/net/html"
+	"recipe.athif.com/internal/data"
+	"recipe.athif.com/internal/ingredientparse"
+)
+
+var ErrNoRecipe = errors.New("no schema.org Recipe found")
+
+// Draft is a recipe extracted from schema.org data, ready to be reviewed before it is
+// saved. Warnings lists anything which couldn't be carried across and will need
+// filling in by hand.
+type Draft struct {
+	Recipe   *data.Recipe `json:"recipe"`
+	Warnings []string     `json:"warnings"`
+}
+
+// ExtractHTML finds the JSON-LD scripts in an HTML document and extracts the first
+// Recipe from them.
+func ExtractHTML(doc []byte) (*Draft, error) {
+	root, err := nethtml.Parse(bytes.NewReader(doc))
+	if err != nil {
+		return nil, err
+	}
+
+	var scripts []string
+	var walk func(n *nethtml.Node)
+	walk = func(n *nethtml.Node) {
+		if n.Type == nethtml.ElementNode && n.Data == "script" && isJSONLD(n) {
+			var sb strings.Builder
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				sb.WriteString(c.Data)
+			}
+			scripts = append(scripts, sb.String())
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+
+	for _, script := range scripts {
+		draft, err := ExtractJSONLD([]byte(script))
+		if err == nil {
+			return draft, nil
+		}
+	}
+	return nil, ErrNoRecipe
+}
+
+func isJSONLD(n *nethtml.Node) bool {
+	for _, attr := range n.Attr {
+		if attr.Key == "type" && strings.EqualFold(strings.TrimSpace(attr.Val), "application/ld+json") {
+			return true
+		}
+	}
+	return false
+}
+
+// ExtractJSONLD extracts the first Recipe from a JSON-LD document, which may be a single
+// object, an array of them or an @graph.
+func ExtractJSONLD(doc []byte) (*Draft, error) {
+	var v any
+	err := json.Unmarshal(doc, &v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON-LD: %w", err)
+	}
+
+	node := findRecipe(v)
+	if node == nil {
+		return nil, ErrNoRecipe
+	}
+	return draftFrom(node), nil
+}
+
+// findRecipe searches depth first for an object whose @type is, or includes, Recipe.
+func findRecipe(v any) map[string]any {
+	switch v := v.(type) {
+	case []any:
+		for _, item := range v {
+			if node := findRecipe(item); node != nil {
+				return node
+			}
+		}
+	case map[string]any:
+		for _, t := range stringList(v["@type"]) {
+			if t == "Recipe" || strings.HasSuffix(t, "/Recipe") {
+				return v
+			}
+		}
+		for _, key := range []string{"@graph", "mainEntity", "mainEntityOfPage"} {
+			if node := findRecipe(v[key]); node != nil {
+				return node
+			}
+		}
+	}
+	return nil
+}
+
+func draftFrom(node map[string]any) *Draft {
+	draft := &Draft{Recipe: &data.Recipe{}, Warnings: []string{}}
+	recipe := draft.Recipe
+	warn := func(format string, args ...any) {
+		draft.Warnings = append(draft.Warnings, fmt.Sprintf(format, args...))
+	}
+
+	recipe.Title = text(firstString(node["name"]))
+	if recipe.Title == "" {
+		warn("name is missing")
+	}
+
+	for _, line := range stringList(node["recipeIngredient"]) {
+		line = text(line)
+		if line == "" {
+			continue
+		}
+		parsed := ingredientparse.Parse(line)
+		if parsed.Name == "" {
+			warn("couldn't find an ingredient name in %q", line)
+			continue
+		}
+		recipe.Ingredients = append(recipe.Ingredients, parsed.Ingredient())
+	}
+	if len(recipe.Ingredients) == 0 {
+		warn("recipeIngredient is missing")
+	}
+
+	recipe.Steps = instructions(node["recipeInstructions"])
+	recipe.SyncInstructions()
+	if len(recipe.Steps) == 0 {
+		warn("recipeInstructions is missing")
+	}
+
+	for field, dst := range map[string]*data.Mins{"prepTime": &recipe.PrepTime, "cookTime": &recipe.CookTime} {
+		s := firstString(node[field])
+		if s == "" {
+			warn("%s is missing", field)
+			continue
+		}
+		m, err := data.ParseMins(s)
+		if err != nil {
+			warn("%s %q isn't a duration", field, s)
+			continue
+		}
+		*dst = m
+	}
+	recipe.TotalTime = recipe.PrepTime + recipe.CookTime
+
+	recipe.CuisineName = text(firstString(node["recipeCuisine"]))
+	if recipe.CuisineName == "" {
+		warn("recipeCuisine is missing")
+	}
+
+	recipe.ImageLink = image(node["image"])
+
+	var tags []string
+	for _, category := range stringList(node["recipeCategory"]) {
+		tags = append(tags, text(category))
+	}
+	for _, diet := range stringList(node["suitableForDiet"]) {
+		// https://schema.org/VeganDiet becomes "vegan".
+		diet = diet[strings.LastIndex(diet, "/")+1:]
+		tags = append(tags, strings.TrimSuffix(diet, "Diet"))
+	}
+	recipe.Tags = data.NormalizeTags(tags)
+	if recipe.Tags == nil {
+		recipe.Tags = []string{}
+	}
+
+	warn("difficulty isn't part of schema.org and must be chosen")
+	return draft
+}
+
+// instructions reads recipeInstructions, which sites give as a single text, a list of
+// texts, a list of HowToStep objects or HowToSection objects holding steps.
+func instructions(v any) []data.Step {
+	var steps []data.Step
+	switch v := v.(type) {
+	case string:
+		steps = data.SplitInstructions(text(v))
+	case []any:
+		for _, item := range v {
+			steps = append(steps, instructions(item)...)
+		}
+	case map[string]any:
+		if items, ok := v["itemListElement"]; ok {
+			return instructions(items)
+		}
+		s := text(firstString(v["text"]))
+		if s == "" {
+			s = text(firstString(v["name"]))
+		}
+		if s != "" {
+			step := data.Step{Text: s, ImageLink: image(v["image"])}
+			steps = append(steps, step)
+		}
+	}
+	for i := range steps {
+		steps[i].Position = i + 1
+	}
+	return steps
+}
+
+// image reads an image given as a URL, an ImageObject or a list of either, returning
+// the first.
+func image(v any) string {
+	switch v := v.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case []any:
+		for _, item := range v {
+			if url := image(item); url != "" {
+				return url
+			}
+		}
+	case map[string]any:
+		return image(v["url"])
+	}
+	return ""
+}
+
+// stringList reads a value which may be a string or a list of strings.
+func stringList(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		var list []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+func firstString(v any) string {
+	list := stringList(v)
+	if len(list) == 0 {
+		return ""
+	}
+	return list[0]
+}
+
+var (
+	tagRX         = regexp.MustCompile(`<[^>]*>`)
+	spaceRX       = regexp.MustCompile(`[ \t\r\f\v]+`)
+	punctuationRX = regexp.MustCompile(` ([.,;:!?])`)
+)
+
+// text strips any markup and entities which sites leave in JSON-LD strings, and
+// collapses runs of spaces.
+func text(s string) string {
+	s = html.UnescapeString(tagRX.ReplaceAllString(s, " "))
+	s = spaceRX.ReplaceAllString(s, " ")
+	return strings.TrimSpace(punctuationRX.ReplaceAllString(s, "$1"))
+}
@@ -0,0 +1,245 @@
+package schemaorg
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"recipe.athif.com/internal/data"
+)
+
+func TestExtractJSONLD(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want *data.Recipe
+	}{
+		{
+			name: "single object",
+			doc: `{
+				"@context": "https://schema.org",
+				"@type": "Recipe",
+				"name": "Pancakes",
+				"recipeIngredient": ["2 1/2 cups flour", "2 eggs"],
+				"recipeInstructions": "1. Mix.\n2. Fry.",
+				"prepTime": "PT10M",
+				"cookTime": "PT20M",
+				"recipeCuisine": "French",
+				"image": "https://example.com/pancakes.jpg"
+			}`,
+			want: &data.Recipe{
+				Title:        "Pancakes",
+				Instructions: "1. Mix.\n2. Fry.",
+				Steps:        []data.Step{{Position: 1, Text: "Mix."}, {Position: 2, Text: "Fry."}},
+				Ingredients: []data.Ingredient{
+					{IngredientName: "flour", Quantity: 2.5, Unit: "cup"},
+					{IngredientName: "eggs", Quantity: 2},
+				},
+				PrepTime:    10,
+				CookTime:    20,
+				TotalTime:   30,
+				CuisineName: "French",
+				ImageLink:   "https://example.com/pancakes.jpg",
+				Tags:        []string{},
+			},
+		},
+		{
+			name: "@graph with an array @type",
+			doc: `{
+				"@context": "https://schema.org",
+				"@graph": [
+					{"@type": "WebSite", "name": "Food Blog"},
+					{"@type": "BreadcrumbList", "itemListElement": []},
+					{
+						"@type": ["Recipe", "NewsArticle"],
+						"name": "Dal",
+						"recipeIngredient": "1 cup lentils",
+						"recipeInstructions": [
+							{"@type": "HowToStep", "text": "Rinse the lentils."},
+							{"@type": "HowToStep", "text": "Simmer.", "image": {"@type": "ImageObject", "url": "https://example.com/simmer.jpg"}}
+						],
+						"prepTime": "PT5M",
+						"cookTime": "PT1H",
+						"recipeCuisine": ["Indian", "Nepali"],
+						"image": [{"@type": "ImageObject", "url": "https://example.com/dal.jpg"}],
+						"recipeCategory": "Main course",
+						"suitableForDiet": "https://schema.org/VeganDiet"
+					}
+				]
+			}`,
+			want: &data.Recipe{
+				Title:        "Dal",
+				Instructions: "1. Rinse the lentils.\n2. Simmer.",
+				Steps: []data.Step{
+					{Position: 1, Text: "Rinse the lentils."},
+					{Position: 2, Text: "Simmer.", ImageLink: "https://example.com/simmer.jpg"},
+				},
+				Ingredients: []data.Ingredient{{IngredientName: "lentils", Quantity: 1, Unit: "cup"}},
+				PrepTime:    5,
+				CookTime:    60,
+				TotalTime:   65,
+				CuisineName: "Indian",
+				ImageLink:   "https://example.com/dal.jpg",
+				Tags:        []string{"main-course", "vegan"},
+			},
+		},
+		{
+			name: "HowToSection instructions and ISO-8601 durations",
+			doc: `[
+				{"@type": "Organization", "name": "Food Blog"},
+				{
+					"@type": "http://schema.org/Recipe",
+					"name": "Roast &amp; <b>Gravy</b>",
+					"recipeIngredient": ["1 chicken"],
+					"recipeInstructions": [
+						{
+							"@type": "HowToSection",
+							"name": "Roast",
+							"itemListElement": [
+								{"@type": "HowToStep", "text": "Season the chicken."},
+								{"@type": "HowToStep", "text": "Roast it."}
+							]
+						},
+						{
+							"@type": "HowToSection",
+							"name": "Gravy",
+							"itemListElement": [{"@type": "HowToStep", "name": "Make the gravy."}]
+						}
+					],
+					"prepTime": "PT0.5H",
+					"cookTime": "P0DT1H30M",
+					"recipeCuisine": "British"
+				}
+			]`,
+			want: &data.Recipe{
+				Title:        "Roast & Gravy",
+				Instructions: "1. Season the chicken.\n2. Roast it.\n3. Make the gravy.",
+				Steps: []data.Step{
+					{Position: 1, Text: "Season the chicken."},
+					{Position: 2, Text: "Roast it."},
+					{Position: 3, Text: "Make the gravy."},
+				},
+				Ingredients: []data.Ingredient{{IngredientName: "chicken", Quantity: 1}},
+				PrepTime:    30,
+				CookTime:    90,
+				TotalTime:   120,
+				CuisineName: "British",
+				Tags:        []string{},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			draft, err := ExtractJSONLD([]byte(tt.doc))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(draft.Recipe, tt.want) {
+				t.Errorf("ExtractJSONLD() =\n%+v\nwant\n%+v", draft.Recipe, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractJSONLDWarnings(t *testing.T) {
+	draft, err := ExtractJSONLD([]byte(`{"@type": "Recipe", "name": "Toast", "prepTime": "a while"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"recipeIngredient is missing",
+		"recipeInstructions is missing",
+		`prepTime "a while" isn't a duration`,
+		"cookTime is missing",
+		"recipeCuisine is missing",
+		"difficulty isn't part of schema.org and must be chosen",
+	}
+	got := map[string]bool{}
+	for _, warning := range draft.Warnings {
+		got[warning] = true
+	}
+	for _, warning := range want {
+		if !got[warning] {
+			t.Errorf("warnings %q don't include %q", draft.Warnings, warning)
+		}
+	}
+	if len(draft.Warnings) != len(want) {
+		t.Errorf("got %d warnings, want %d: %q", len(draft.Warnings), len(want), draft.Warnings)
+	}
+}
+
+func TestExtractJSONLDErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		noRecipe bool
+	}{
+		{"invalid JSON", `{"@type": "Recipe",`, false},
+		{"no recipe", `{"@type": "Article", "name": "News"}`, true},
+		{"no recipe in @graph", `{"@graph": [{"@type": "WebSite"}]}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			draft, err := ExtractJSONLD([]byte(tt.doc))
+			if err == nil {
+				t.Fatalf("ExtractJSONLD() = %+v, want an error", draft)
+			}
+			if got := errors.Is(err, ErrNoRecipe); got != tt.noRecipe {
+				t.Errorf("ExtractJSONLD() error = %v, want ErrNoRecipe: %v", err, tt.noRecipe)
+			}
+		})
+	}
+}
+
+func TestExtractHTML(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		title string
+		err   error
+	}{
+		{
+			name: "recipe after other JSON-LD",
+			doc: `<!DOCTYPE html>
+<html><head>
+<script type="application/ld+json">{"@type": "Organization", "name": "Food Blog"}</script>
+<script type="application/json">{"@type": "Recipe", "name": "Not JSON-LD"}</script>
+</head><body>
+<script type="Application/LD+JSON ">
+{"@context": "https://schema.org", "@graph": [{"@type": ["Recipe"], "name": "Soup", "cookTime": "PT45M"}]}
+</script>
+</body></html>`,
+			title: "Soup",
+		},
+		{
+			name: "broken JSON-LD before the recipe",
+			doc: `<html><head>
+<script type="application/ld+json">{"@type": "Recipe", </script>
+<script type="application/ld+json">{"@type": "Recipe", "name": "Stew"}</script>
+</head></html>`,
+			title: "Stew",
+		},
+		{
+			name: "no JSON-LD",
+			doc:  `<html><body><h1>Soup</h1></body></html>`,
+			err:  ErrNoRecipe,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			draft, err := ExtractHTML([]byte(tt.doc))
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Errorf("ExtractHTML() error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if draft.Recipe.Title != tt.title {
+				t.Errorf("ExtractHTML() title = %q, want %q", draft.Recipe.Title, tt.title)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+// Package similarity scores how alike two recipes are from the ingredients they share.
+//
+// The score is a weighted Jaccard index: the weight of the ingredients two recipes
+// share divided by the weight of all the ingredients either of them uses. Each
+// ingredient is weighted by its inverse document frequency, so staples such as salt
+// and water, which most recipes use, count for little, while a rare ingredient in
+// common counts for a lot. The index is then boosted when the recipes share a cuisine
+// or a difficulty.
+package similarity
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	// CuisineBoost and DifficultyBoost are the fractions added to the score of recipes
+	// which share a cuisine or a difficulty.
+	CuisineBoost    = 0.25
+	DifficultyBoost = 0.1
+	// MinScore is the lowest score worth keeping. Pairs which only share a staple or two
+	// fall below it.
+	MinScore = 0.05
+)
+
+// Recipe is what the score is computed from. Ingredients are catalog IDs.
+type Recipe struct {
+	ID          int
+	CuisineID   int
+	Difficulty  string
+	Ingredients []int
+}
+
+// Pair is the score of SimilarID as a recommendation for RecipeID.
+type Pair struct {
+	RecipeID  int
+	SimilarID int
+	Score     float64
+}
+
+// Index holds a corpus of recipes along with the ingredient weights derived from it.
+type Index struct {
+	recipes map[int]*Recipe
+	weights map[int]float64
+	// users lists the recipes using each ingredient, so that only recipes sharing at
+	// least one ingredient are ever compared.
+	users map[int][]int
+}
+
+// NewIndex builds an index over the given recipes.
+func NewIndex(recipes []Recipe) *Index {
+	ix := &Index{
+		recipes: make(map[int]*Recipe, len(recipes)),
+		weights: map[int]float64{},
+		users:   map[int][]int{},
+	}
+	for i := range recipes {
+		recipe := &recipes[i]
+		ix.recipes[recipe.ID] = recipe
+		seen := make(map[int]bool, len(recipe.Ingredients))
+		for _, ingredient := range recipe.Ingredients {
+			if !seen[ingredient] {
+				seen[ingredient] = true
+				ix.users[ingredient] = append(ix.users[ingredient], recipe.ID)
+			}
+		}
+	}
+	// The weight is smoothed so that an ingredient used by every recipe still counts
+	// for something.
+	n := float64(len(recipes))
+	for ingredient, users := range ix.users {
+		ix.weights[ingredient] = math.Log(1 + n/float64(len(users)))
+	}
+	return ix
+}
+
+// Len returns the number of recipes in the index.
+func (ix *Index) Len() int {
+	return len(ix.recipes)
+}
+
+// Score returns how similar recipe b is to recipe a, from zero for recipes without an
+// ingredient in common up to 1 + CuisineBoost + DifficultyBoost.
+func (ix *Index) Score(a, b *Recipe) float64 {
+	inA := make(map[int]bool, len(a.Ingredients))
+	var union float64
+	for _, ingredient := range a.Ingredients {
+		if !inA[ingredient] {
+			inA[ingredient] = true
+			union += ix.weights[ingredient]
+		}
+	}
+	var shared float64
+	seen := make(map[int]bool, len(b.Ingredients))
+	for _, ingredient := range b.Ingredients {
+		if seen[ingredient] {
+			continue
+		}
+		seen[ingredient] = true
+		if inA[ingredient] {
+			shared += ix.weights[ingredient]
+		} else {
+			union += ix.weights[ingredient]
+		}
+	}
+	if shared == 0 {
+		return 0
+	}
+
+	boost := 1.0
+	if a.CuisineID == b.CuisineID {
+		boost += CuisineBoost
+	}
+	if a.Difficulty == b.Difficulty {
+		boost += DifficultyBoost
+	}
+	return shared / union * boost
+}
+
+// Similar returns up to k of the recipes most similar to the one with the given ID,
+// best first, leaving out those scoring below MinScore. It returns nothing for a
+// recipe which isn't in the index.
+func (ix *Index) Similar(id, k int) []Pair {
+	recipe, ok := ix.recipes[id]
+	if !ok {
+		return nil
+	}
+
+	var pairs []Pair
+	for _, candidate := range ix.candidates(recipe) {
+		score := ix.Score(recipe, ix.recipes[candidate])
+		if score >= MinScore {
+			pairs = append(pairs, Pair{RecipeID: id, SimilarID: candidate, Score: score})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Score != pairs[j].Score {
+			return pairs[i].Score > pairs[j].Score
+		}
+		return pairs[i].SimilarID < pairs[j].SimilarID
+	})
+	if len(pairs) > k {
+		pairs = pairs[:k]
+	}
+	return pairs
+}
+
+// All returns the top k pairs for every recipe in the index.
+func (ix *Index) All(k int) []Pair {
+	var pairs []Pair
+	for id := range ix.recipes {
+		pairs = append(pairs, ix.Similar(id, k)...)
+	}
+	return pairs
+}
+
+// Refresh returns the pairs to store after the recipes with the given IDs changed:
+// the top k for each of them, and each of them as a recommendation for the recipes
+// it is similar to. The latter are not limited to k, so a recipe may hold a few more
+// than k pairs until the next full rebuild.
+func (ix *Index) Refresh(ids []int, k int) []Pair {
+	changed := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		changed[id] = true
+	}
+
+	var pairs []Pair
+	for _, id := range ids {
+		recipe, ok := ix.recipes[id]
+		if !ok {
+			continue
+		}
+		pairs = append(pairs, ix.Similar(id, k)...)
+		for _, candidate := range ix.candidates(recipe) {
+			// Pairs between two changed recipes are already covered by Similar.
+			if changed[candidate] {
+				continue
+			}
+			other := ix.recipes[candidate]
+			if score := ix.Score(other, recipe); score >= MinScore {
+				pairs = append(pairs, Pair{RecipeID: candidate, SimilarID: id, Score: score})
+			}
+		}
+	}
+	return pairs
+}
+
+// candidates returns the other recipes sharing at least one ingredient with recipe.
+func (ix *Index) candidates(recipe *Recipe) []int {
+	seen := map[int]bool{recipe.ID: true}
+	var candidates []int
+	for _, ingredient := range recipe.Ingredients {
+		for _, id := range ix.users[ingredient] {
+			if !seen[id] {
+				seen[id] = true
+				candidates = append(candidates, id)
+			}
+		}
+	}
+	return candidates
+}
@@ -0,0 +1,250 @@
+package similarity
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// The ingredients of the test corpus. Salt is in every recipe, and saffron in two.
+const (
+	salt = iota + 1
+	tomato
+	basil
+	garlic
+	saffron
+	rice
+)
+
+func corpus() []Recipe {
+	return []Recipe{
+		{ID: 1, CuisineID: 1, Difficulty: "Easy", Ingredients: []int{salt, tomato, basil, garlic}},
+		{ID: 2, CuisineID: 1, Difficulty: "Easy", Ingredients: []int{salt, tomato, basil, garlic}},
+		{ID: 3, CuisineID: 1, Difficulty: "Medium", Ingredients: []int{salt, tomato, garlic}},
+		{ID: 4, CuisineID: 2, Difficulty: "Medium", Ingredients: []int{salt, saffron, rice}},
+		{ID: 5, CuisineID: 3, Difficulty: "Easy", Ingredients: []int{salt, saffron, rice}},
+		{ID: 6, CuisineID: 3, Difficulty: "Easy", Ingredients: []int{salt, rice}},
+		{ID: 7, CuisineID: 4, Difficulty: "Advanced", Ingredients: []int{salt}},
+		{ID: 8, CuisineID: 4, Difficulty: "Advanced", Ingredients: nil},
+	}
+}
+
+func get(t *testing.T, ix *Index, id int) *Recipe {
+	t.Helper()
+	recipe, ok := ix.recipes[id]
+	if !ok {
+		t.Fatalf("recipe %d is not in the index", id)
+	}
+	return recipe
+}
+
+func TestScore(t *testing.T) {
+	ix := NewIndex(corpus())
+
+	tests := []struct {
+		name string
+		a, b int
+		want float64
+	}{
+		{"same ingredients, cuisine and difficulty", 1, 2, 1 + CuisineBoost + DifficultyBoost},
+		{"no ingredients", 7, 8, 0},
+		{"same cuisine and difficulty, no ingredients", 8, 7, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ix.Score(get(t, ix, tt.a), get(t, ix, tt.b))
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Score(%d, %d) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("weighted jaccard", func(t *testing.T) {
+		// Recipes 4 and 5 share salt, saffron and rice, and nothing else, so without a
+		// boost the index is one.
+		got := ix.Score(get(t, ix, 4), get(t, ix, 5))
+		if math.Abs(got-1) > 1e-9 {
+			t.Errorf("Score(4, 5) = %v, want 1", got)
+		}
+
+		// Recipe 3 shares everything but basil with recipe 1.
+		n := 8.0
+		w := func(users float64) float64 { return math.Log(1 + n/users) }
+		want := (w(7) + w(3) + w(3)) / (w(7) + w(3) + w(2) + w(3)) * (1 + CuisineBoost)
+		if got := ix.Score(get(t, ix, 1), get(t, ix, 3)); math.Abs(got-want) > 1e-9 {
+			t.Errorf("Score(1, 3) = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("rare ingredients count for more than staples", func(t *testing.T) {
+		// Recipe 4 shares saffron and rice with 5 but only rice with 6, and 5 and 6 are
+		// otherwise alike.
+		rare := ix.Score(get(t, ix, 4), get(t, ix, 5))
+		common := ix.Score(get(t, ix, 4), get(t, ix, 6))
+		if rare <= common {
+			t.Errorf("sharing saffron scored %v, not above %v", rare, common)
+		}
+		if staple := ix.Score(get(t, ix, 1), get(t, ix, 7)); staple >= common {
+			t.Errorf("sharing only salt scored %v, not below %v", staple, common)
+		}
+	})
+
+	t.Run("symmetric apart from the boosts", func(t *testing.T) {
+		for _, a := range corpus() {
+			for _, b := range corpus() {
+				ab := ix.Score(get(t, ix, a.ID), get(t, ix, b.ID))
+				ba := ix.Score(get(t, ix, b.ID), get(t, ix, a.ID))
+				if math.Abs(ab-ba) > 1e-9 {
+					t.Errorf("Score(%d, %d) = %v but Score(%d, %d) = %v", a.ID, b.ID, ab, b.ID, a.ID, ba)
+				}
+				if ab < 0 || ab > 1+CuisineBoost+DifficultyBoost+1e-9 {
+					t.Errorf("Score(%d, %d) = %v is out of range", a.ID, b.ID, ab)
+				}
+			}
+		}
+	})
+
+	t.Run("repeated ingredients count once", func(t *testing.T) {
+		recipes := corpus()
+		recipes[0].Ingredients = []int{salt, tomato, tomato, basil, garlic, garlic}
+		repeated := NewIndex(recipes)
+		for _, id := range []int{2, 3, 7} {
+			got := repeated.Score(get(t, repeated, 1), get(t, repeated, id))
+			want := ix.Score(get(t, ix, 1), get(t, ix, id))
+			if math.Abs(got-want) > 1e-9 {
+				t.Errorf("Score(1, %d) = %v with repeats, want %v", id, got, want)
+			}
+		}
+	})
+}
+
+func TestSimilar(t *testing.T) {
+	ix := NewIndex(corpus())
+
+	t.Run("best first", func(t *testing.T) {
+		pairs := ix.Similar(1, 10)
+		if len(pairs) == 0 {
+			t.Fatal("Similar(1) returned nothing")
+		}
+		if pairs[0].SimilarID != 2 {
+			t.Errorf("the most similar recipe to 1 is %d, want 2", pairs[0].SimilarID)
+		}
+		for i, pair := range pairs {
+			if pair.RecipeID != 1 {
+				t.Errorf("pair %d is for recipe %d, want 1", i, pair.RecipeID)
+			}
+			if pair.SimilarID == 1 {
+				t.Error("recipe 1 is similar to itself")
+			}
+			if pair.Score < MinScore {
+				t.Errorf("pair %d scores %v, below MinScore", i, pair.Score)
+			}
+			if i > 0 && pair.Score > pairs[i-1].Score {
+				t.Errorf("pair %d scores %v, above the one before it", i, pair.Score)
+			}
+		}
+	})
+
+	t.Run("ties by ID", func(t *testing.T) {
+		// Recipes 1 and 2 are identical, so they score the same against recipe 3.
+		pairs := ix.Similar(3, 2)
+		want := []int{1, 2}
+		var got []int
+		for _, pair := range pairs {
+			got = append(got, pair.SimilarID)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Similar(3, 2) = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("at most k", func(t *testing.T) {
+		if pairs := ix.Similar(1, 1); len(pairs) != 1 {
+			t.Errorf("Similar(1, 1) returned %d pairs", len(pairs))
+		}
+	})
+
+	t.Run("staples alone fall below MinScore", func(t *testing.T) {
+		// Salt is the only ingredient in common, and it is in every recipe but one.
+		recipes := make([]Recipe, 0, 40)
+		for i := 1; i <= 40; i++ {
+			recipes = append(recipes, Recipe{ID: i, CuisineID: i, Difficulty: "Easy", Ingredients: []int{salt, 100 + i, 200 + i, 300 + i}})
+		}
+		if pairs := NewIndex(recipes).Similar(1, 10); len(pairs) != 0 {
+			t.Errorf("Similar(1) = %v, want nothing", pairs)
+		}
+	})
+
+	t.Run("unknown recipe", func(t *testing.T) {
+		if pairs := ix.Similar(99, 10); pairs != nil {
+			t.Errorf("Similar(99) = %v, want nil", pairs)
+		}
+	})
+}
+
+func TestRefresh(t *testing.T) {
+	ix := NewIndex(corpus())
+	const k = 3
+
+	// Refreshing every recipe stores the same top k pairs as a full rebuild, along with
+	// nothing else.
+	all := ix.All(k)
+	var ids []int
+	for _, recipe := range corpus() {
+		ids = append(ids, recipe.ID)
+	}
+	refreshed := ix.Refresh(ids, k)
+	sortPairs(all)
+	sortPairs(refreshed)
+	if !reflect.DeepEqual(refreshed, all) {
+		t.Errorf("Refresh(all) = %v, want %v", refreshed, all)
+	}
+
+	// Refreshing one recipe stores its top k, and it as a recommendation for every
+	// recipe it is similar to.
+	pairs := ix.Refresh([]int{4}, k)
+	for _, want := range ix.Similar(4, k) {
+		if !containsPair(pairs, want) {
+			t.Errorf("Refresh([4]) is missing %v", want)
+		}
+	}
+	for _, pair := range pairs {
+		if pair.RecipeID != 4 && pair.SimilarID != 4 {
+			t.Errorf("Refresh([4]) returned %v, which doesn't involve recipe 4", pair)
+		}
+		if pair.RecipeID != 4 {
+			want := ix.Score(get(t, ix, pair.RecipeID), get(t, ix, 4))
+			if math.Abs(pair.Score-want) > 1e-9 {
+				t.Errorf("Refresh([4]) scored %v at %v, want %v", pair, pair.Score, want)
+			}
+		}
+	}
+	for _, id := range []int{5, 6} {
+		if !containsPair(pairs, Pair{RecipeID: id, SimilarID: 4, Score: ix.Score(get(t, ix, id), get(t, ix, 4))}) {
+			t.Errorf("Refresh([4]) doesn't recommend 4 for %d", id)
+		}
+	}
+
+	if pairs := ix.Refresh([]int{99}, k); len(pairs) != 0 {
+		t.Errorf("Refresh([99]) = %v, want nothing", pairs)
+	}
+}
+
+func sortPairs(pairs []Pair) {
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].RecipeID != pairs[j].RecipeID {
+			return pairs[i].RecipeID < pairs[j].RecipeID
+		}
+		return pairs[i].SimilarID < pairs[j].SimilarID
+	})
+}
+
+func containsPair(pairs []Pair, want Pair) bool {
+	for _, pair := range pairs {
+		if pair.RecipeID == want.RecipeID && pair.SimilarID == want.SimilarID && math.Abs(pair.Score-want.Score) < 1e-9 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,6 @@
+DROP VIEW IF EXISTS recipe_view;
+DROP TABLE IF EXISTS recipe_images;
+DROP TABLE IF EXISTS recipeingredients;
+DROP TABLE IF EXISTS ingredients;
+DROP TABLE IF EXISTS recipes;
+DROP TABLE IF EXISTS cuisine;
@@ -0,0 +1,42 @@
+-- The tables below predate the migrations directory. IF NOT EXISTS lets this migration
+-- be applied to databases that were created by hand.
+CREATE TABLE IF NOT EXISTS cuisine (
+    cuisineid serial PRIMARY KEY,
+    cuisinename text NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS recipes (
+    recipeid serial PRIMARY KEY,
+    recipename text NOT NULL,
+    instructions text NOT NULL,
+    preparationtime integer NOT NULL,
+    cookingtime integer NOT NULL,
+    difficultylevel text NOT NULL,
+    cuisineid integer REFERENCES cuisine (cuisineid),
+    cuisinename text
+);
+
+CREATE TABLE IF NOT EXISTS ingredients (
+    ingredientid serial PRIMARY KEY,
+    ingredientname text NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS recipeingredients (
+    recipeid integer NOT NULL REFERENCES recipes (recipeid) ON DELETE CASCADE,
+    ingredientid integer NOT NULL REFERENCES ingredients (ingredientid),
+    quantity real NOT NULL DEFAULT 0,
+    unit text NOT NULL DEFAULT '',
+    PRIMARY KEY (recipeid, ingredientid)
+);
+
+CREATE TABLE IF NOT EXISTS recipe_images (
+    recipeid integer PRIMARY KEY REFERENCES recipes (recipeid) ON DELETE CASCADE,
+    imagelink text NOT NULL
+);
+
+CREATE OR REPLACE VIEW recipe_view AS
+SELECT r.recipeid, r.recipename, r.instructions, r.preparationtime, r.cookingtime,
+       r.difficultylevel, r.cuisineid, i.ingredientname, ri.quantity, ri.unit
+FROM recipes r
+INNER JOIN recipeingredients ri ON r.recipeid = ri.recipeid
+INNER JOIN ingredients i ON ri.ingredientid = i.ingredientid;
@@ -0,0 +1 @@
+ALTER TABLE recipes DROP COLUMN IF EXISTS updated_at;
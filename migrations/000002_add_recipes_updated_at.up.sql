@@ -0,0 +1 @@
+ALTER TABLE recipes ADD COLUMN IF NOT EXISTS updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW();
@@ -0,0 +1,4 @@
+ALTER TABLE recipes ADD COLUMN IF NOT EXISTS cuisinename text;
+UPDATE recipes r SET cuisinename = c.cuisinename FROM cuisine c WHERE r.cuisineid = c.cuisineid;
+DROP INDEX IF EXISTS cuisine_slug_idx;
+ALTER TABLE cuisine DROP COLUMN IF EXISTS slug;
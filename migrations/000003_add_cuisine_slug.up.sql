@@ -0,0 +1,11 @@
+ALTER TABLE cuisine ADD COLUMN IF NOT EXISTS slug text;
+UPDATE cuisine SET slug = trim(both '-' from regexp_replace(lower(cuisinename), '[^a-z0-9]+', '-', 'g'));
+ALTER TABLE cuisine ALTER COLUMN slug SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS cuisine_slug_idx ON cuisine (slug);
+
+-- Recipes used to be inserted with only the denormalised cuisine name, leaving
+-- cuisineid (which every read joins on) empty. Fill it in and drop the copy.
+UPDATE recipes r SET cuisineid = c.cuisineid
+FROM cuisine c
+WHERE r.cuisineid IS NULL AND r.cuisinename = c.cuisinename;
+ALTER TABLE recipes DROP COLUMN IF EXISTS cuisinename;
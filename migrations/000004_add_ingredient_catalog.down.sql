@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS ingredient_aliases;
+ALTER TABLE ingredients DROP COLUMN IF EXISTS category;
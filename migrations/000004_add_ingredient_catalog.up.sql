@@ -0,0 +1,10 @@
+ALTER TABLE ingredients ADD COLUMN IF NOT EXISTS category text NOT NULL DEFAULT '';
+
+-- Aliases are stored lower-case and are unique across the catalog, so that a name
+-- resolves to at most one ingredient.
+CREATE TABLE IF NOT EXISTS ingredient_aliases (
+    alias text PRIMARY KEY,
+    ingredientid integer NOT NULL REFERENCES ingredients (ingredientid) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS ingredient_aliases_ingredientid_idx ON ingredient_aliases (ingredientid);
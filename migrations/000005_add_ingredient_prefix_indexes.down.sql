@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS recipeingredients_ingredientid_idx;
+DROP INDEX IF EXISTS ingredient_aliases_prefix_idx;
+DROP INDEX IF EXISTS ingredients_name_prefix_idx;
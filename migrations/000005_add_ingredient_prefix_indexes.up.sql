@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS ingredients_name_prefix_idx ON ingredients (LOWER(ingredientname) text_pattern_ops);
+CREATE INDEX IF NOT EXISTS ingredient_aliases_prefix_idx ON ingredient_aliases (alias text_pattern_ops);
+CREATE INDEX IF NOT EXISTS recipeingredients_ingredientid_idx ON recipeingredients (ingredientid);
@@ -0,0 +1,36 @@
+CREATE TABLE IF NOT EXISTS recipe_steps (
+    recipeid INT NOT NULL REFERENCES recipes(recipeid) ON DELETE CASCADE,
+    position INT NOT NULL,
+    body TEXT NOT NULL,
+    duration INT,
+    ingredients JSONB NOT NULL DEFAULT '[]',
+    imagelink TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (recipeid, position)
+);
+
+-- Split the existing instructions into steps, following data.SplitInstructions: a line
+-- starting with "1.", "2)" etc. begins a step and the lines after it are folded in.
+-- Lines before the first numbered one, or in text without numbering, are a step each.
+WITH lines AS (
+    SELECT r.recipeid, l.lineno, btrim(l.line, E' \t\r') AS line
+    FROM recipes r,
+    regexp_split_to_table(r.instructions, E'\n') WITH ORDINALITY AS l(line, lineno)
+), marked AS (
+    SELECT recipeid, lineno,
+        regexp_replace(line, E'^\\s*\\d+\\s*[.):-]\\s*', '') AS line,
+        SUM(CASE WHEN line ~ E'^\\s*\\d+\\s*[.):-]' THEN 1 ELSE 0 END)
+            OVER (PARTITION BY recipeid ORDER BY lineno) AS numbered
+    FROM lines
+    WHERE line <> ''
+), grouped AS (
+    SELECT recipeid,
+        MIN(lineno) AS firstline,
+        string_agg(line, E'\n' ORDER BY lineno) FILTER (WHERE line <> '') AS body
+    FROM marked
+    GROUP BY recipeid, CASE WHEN numbered = 0 THEN -lineno ELSE numbered END
+)
+INSERT INTO recipe_steps (recipeid, position, body)
+SELECT recipeid, ROW_NUMBER() OVER (PARTITION BY recipeid ORDER BY firstline), body
+FROM grouped
+WHERE body IS NOT NULL
+ON CONFLICT DO NOTHING;
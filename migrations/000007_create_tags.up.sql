@@ -0,0 +1,37 @@
+CREATE TABLE IF NOT EXISTS tags (
+    tagid SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    category TEXT NOT NULL DEFAULT 'free'
+);
+
+CREATE TABLE IF NOT EXISTS recipe_tags (
+    recipeid INT NOT NULL REFERENCES recipes(recipeid) ON DELETE CASCADE,
+    tagid INT NOT NULL REFERENCES tags(tagid) ON DELETE CASCADE,
+    PRIMARY KEY (recipeid, tagid)
+);
+
+CREATE INDEX IF NOT EXISTS recipe_tags_tagid_idx ON recipe_tags (tagid);
+
+-- The controlled vocabulary. Any other tag a recipe is given is stored as a free tag.
+INSERT INTO tags (name, category) VALUES
+    ('vegetarian', 'diet'),
+    ('vegan', 'diet'),
+    ('pescatarian', 'diet'),
+    ('gluten-free', 'diet'),
+    ('dairy-free', 'diet'),
+    ('nut-free', 'diet'),
+    ('breakfast', 'course'),
+    ('lunch', 'course'),
+    ('dinner', 'course'),
+    ('starter', 'course'),
+    ('main', 'course'),
+    ('side', 'course'),
+    ('dessert', 'course'),
+    ('snack', 'course'),
+    ('drink', 'course'),
+    ('quick', 'label'),
+    ('one-pot', 'label'),
+    ('make-ahead', 'label'),
+    ('budget', 'label'),
+    ('kid-friendly', 'label')
+ON CONFLICT (name) DO UPDATE SET category = EXCLUDED.category;
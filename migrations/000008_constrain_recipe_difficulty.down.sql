@@ -0,0 +1 @@
+ALTER TABLE recipes DROP CONSTRAINT IF EXISTS recipes_difficultylevel_check;
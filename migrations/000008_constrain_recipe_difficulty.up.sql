@@ -0,0 +1,36 @@
+-- Difficulty used to be free text. Normalise the capitalisation of the known levels and
+-- map the common synonyms before constraining the column. Anything else can't be placed
+-- safely, so the migration stops and lists those recipes, to be fixed by hand before it
+-- is run again.
+UPDATE recipes SET difficultylevel = CASE LOWER(btrim(difficultylevel))
+    WHEN 'easy' THEN 'Easy'
+    WHEN 'beginner' THEN 'Easy'
+    WHEN 'simple' THEN 'Easy'
+    WHEN 'medium' THEN 'Medium'
+    WHEN 'intermediate' THEN 'Medium'
+    WHEN 'moderate' THEN 'Medium'
+    WHEN 'advanced' THEN 'Advanced'
+    WHEN 'hard' THEN 'Advanced'
+    WHEN 'difficult' THEN 'Advanced'
+    ELSE difficultylevel
+END
+WHERE difficultylevel NOT IN ('Easy', 'Medium', 'Advanced');
+
+DO $$
+DECLARE
+    unknown text;
+BEGIN
+    SELECT string_agg(format('%s (%L)', recipeid, difficultylevel), ', ' ORDER BY recipeid)
+    INTO unknown
+    FROM recipes
+    WHERE difficultylevel NOT IN ('Easy', 'Medium', 'Advanced');
+
+    IF unknown IS NOT NULL THEN
+        RAISE EXCEPTION 'recipes with an unrecognised difficulty: %', unknown
+            USING HINT = 'Set their difficultylevel to Easy, Medium or Advanced and run the migration again.';
+    END IF;
+END
+$$;
+
+ALTER TABLE recipes ADD CONSTRAINT recipes_difficultylevel_check
+    CHECK (difficultylevel IN ('Easy', 'Medium', 'Advanced'));
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS recipes_uid_idx;
+ALTER TABLE recipes DROP COLUMN IF EXISTS uid;
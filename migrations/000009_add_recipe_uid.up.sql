@@ -0,0 +1,4 @@
+-- Recipe IDs are serials local to one database. The uid identifies a recipe across
+-- environments, so restoring a backup can update recipes it has already restored.
+ALTER TABLE recipes ADD COLUMN IF NOT EXISTS uid uuid NOT NULL DEFAULT gen_random_uuid();
+CREATE UNIQUE INDEX IF NOT EXISTS recipes_uid_idx ON recipes (uid);
@@ -0,0 +1,3 @@
+DELETE FROM recipes WHERE deleted_at IS NOT NULL;
+DROP INDEX IF EXISTS recipes_deleted_at_idx;
+ALTER TABLE recipes DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,3 @@
+-- Deleted recipes are kept in the trash, with deleted_at set, until they are purged.
+ALTER TABLE recipes ADD COLUMN IF NOT EXISTS deleted_at timestamp(0) with time zone;
+CREATE INDEX IF NOT EXISTS recipes_deleted_at_idx ON recipes (deleted_at) WHERE deleted_at IS NOT NULL;
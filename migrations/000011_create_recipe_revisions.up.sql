@@ -0,0 +1,10 @@
+-- Each revision is a full snapshot of the recipe as it was saved, so any two can be
+-- compared and any one restored without replaying the others.
+CREATE TABLE IF NOT EXISTS recipe_revisions (
+    recipeid integer NOT NULL REFERENCES recipes (recipeid) ON DELETE CASCADE,
+    revision integer NOT NULL,
+    snapshot jsonb NOT NULL,
+    author text NOT NULL DEFAULT '',
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (recipeid, revision)
+);
@@ -0,0 +1,19 @@
+-- Every mutating API request is recorded here, whether or not it succeeded. The table
+-- is append-only: nothing in the API updates or deletes its rows.
+CREATE TABLE IF NOT EXISTS audit_events (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    actor text NOT NULL,
+    request_id text NOT NULL,
+    ip text NOT NULL,
+    method text NOT NULL,
+    route text NOT NULL,
+    status integer NOT NULL,
+    resource_type text NOT NULL DEFAULT '',
+    resource_id bigint,
+    changes jsonb NOT NULL DEFAULT '[]'
+);
+
+CREATE INDEX IF NOT EXISTS audit_events_created_at_idx ON audit_events (created_at);
+CREATE INDEX IF NOT EXISTS audit_events_actor_idx ON audit_events (actor);
+CREATE INDEX IF NOT EXISTS audit_events_resource_idx ON audit_events (resource_type, resource_id);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS recipes_publish_at_idx;
+DROP INDEX IF EXISTS recipes_status_idx;
+ALTER TABLE recipes DROP COLUMN IF EXISTS publish_at;
+ALTER TABLE recipes DROP COLUMN IF EXISTS review_reason;
+ALTER TABLE recipes DROP COLUMN IF EXISTS author;
+ALTER TABLE recipes DROP CONSTRAINT IF EXISTS recipes_status_check;
+ALTER TABLE recipes DROP COLUMN IF EXISTS status;
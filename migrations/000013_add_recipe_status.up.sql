@@ -0,0 +1,17 @@
+-- Recipes go through a publishing workflow. Those created before it existed were
+-- already public, so they start out published.
+ALTER TABLE recipes ADD COLUMN IF NOT EXISTS status text NOT NULL DEFAULT 'published';
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'recipes_status_check' AND conrelid = 'recipes'::regclass) THEN
+        ALTER TABLE recipes ADD CONSTRAINT recipes_status_check CHECK (status IN ('draft', 'pending_review', 'published', 'rejected'));
+    END IF;
+END
+$$;
+ALTER TABLE recipes ALTER COLUMN status SET DEFAULT 'draft';
+ALTER TABLE recipes ADD COLUMN IF NOT EXISTS author text NOT NULL DEFAULT '';
+ALTER TABLE recipes ADD COLUMN IF NOT EXISTS review_reason text NOT NULL DEFAULT '';
+ALTER TABLE recipes ADD COLUMN IF NOT EXISTS publish_at timestamp(0) with time zone;
+
+CREATE INDEX IF NOT EXISTS recipes_status_idx ON recipes (status) WHERE status <> 'published';
+CREATE INDEX IF NOT EXISTS recipes_publish_at_idx ON recipes (publish_at) WHERE status = 'pending_review';
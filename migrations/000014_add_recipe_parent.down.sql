@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS recipes_parent_id_idx;
+ALTER TABLE recipes DROP COLUMN IF EXISTS parent_id;
@@ -0,0 +1,4 @@
+-- A fork records the recipe it was copied from. Forks outlive their parent, so purging
+-- the parent only clears the link.
+ALTER TABLE recipes ADD COLUMN IF NOT EXISTS parent_id integer REFERENCES recipes (recipeid) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS recipes_parent_id_idx ON recipes (parent_id) WHERE parent_id IS NOT NULL;
@@ -0,0 +1,11 @@
+-- Precomputed by the API's similarity job: the recipes most like each recipe, best
+-- first. Both sides are dropped along with the recipe they refer to.
+CREATE TABLE IF NOT EXISTS recipe_similarities (
+    recipeid integer NOT NULL REFERENCES recipes (recipeid) ON DELETE CASCADE,
+    similarid integer NOT NULL REFERENCES recipes (recipeid) ON DELETE CASCADE,
+    score real NOT NULL,
+    PRIMARY KEY (recipeid, similarid)
+);
+
+CREATE INDEX IF NOT EXISTS recipe_similarities_score_idx ON recipe_similarities (recipeid, score DESC);
+CREATE INDEX IF NOT EXISTS recipe_similarities_similarid_idx ON recipe_similarities (similarid);
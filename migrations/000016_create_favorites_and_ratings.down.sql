@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS recipe_ratings;
+DROP TABLE IF EXISTS recipe_favorites;
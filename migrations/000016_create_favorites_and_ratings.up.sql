@@ -0,0 +1,19 @@
+-- Favorites and ratings belong to the same actor that authors recipes: the admin, or
+-- the author named by the request's token. Anonymous requests can't add either.
+CREATE TABLE IF NOT EXISTS recipe_favorites (
+    actor text NOT NULL,
+    recipeid integer NOT NULL REFERENCES recipes (recipeid) ON DELETE CASCADE,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (actor, recipeid)
+);
+
+CREATE TABLE IF NOT EXISTS recipe_ratings (
+    actor text NOT NULL,
+    recipeid integer NOT NULL REFERENCES recipes (recipeid) ON DELETE CASCADE,
+    rating smallint NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (actor, recipeid)
+);
+
+CREATE INDEX IF NOT EXISTS recipe_favorites_recipeid_idx ON recipe_favorites (recipeid);
+CREATE INDEX IF NOT EXISTS recipe_ratings_recipeid_idx ON recipe_ratings (recipeid);
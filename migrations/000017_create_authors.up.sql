@@ -0,0 +1,10 @@
+-- Authors identify themselves with a bearer token issued when they register. Only a
+-- hash of the token is kept. Recipes, favorites and ratings written before authors
+-- existed are keyed by an IP address, which no author name can match, so they stay
+-- with the admin.
+CREATE TABLE IF NOT EXISTS authors (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL UNIQUE,
+    token_hash bytea NOT NULL UNIQUE,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
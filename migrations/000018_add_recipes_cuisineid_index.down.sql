@@ -0,0 +1 @@
+DROP INDEX IF EXISTS recipes_published_cuisineid_idx;
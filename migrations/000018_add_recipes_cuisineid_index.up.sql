@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS recipes_published_cuisineid_idx ON recipes (cuisineid) WHERE status = 'published' AND deleted_at IS NULL;